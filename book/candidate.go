@@ -0,0 +1,35 @@
+package book
+
+import (
+	"strings"
+)
+
+// candidate is a group of links sharing the same path in the page, that may be the table of contents
+type candidate struct {
+	Selector string `json:"selector"`
+	Score    int    `json:"score"`
+	Links    []link `json:"links"`
+}
+
+// FormatPath turns a path returned by GetPath and the class of the last element into a CSS selector.
+func FormatPath(path, class string) string {
+	elements := []string{}
+
+	// GetPath starts from the element and goes up to the document
+	parts := strings.Split(path, "<")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if len(parts[i]) == 0 || strings.HasPrefix(parts[i], "#") {
+			continue
+		}
+		elements = append(elements, parts[i])
+	}
+
+	selector := strings.Join(elements, ">")
+
+	classes := strings.Fields(class)
+	if len(classes) > 0 {
+		selector += "." + strings.Join(classes, ".")
+	}
+
+	return selector
+}
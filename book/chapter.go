@@ -1,21 +1,24 @@
 package book
 
 type chapter struct {
-	url        string
+	url         string
 	body        string
 	name        string
 	author      string
 	content     string
 	subChapters []chapter
 	config      *ScrapeConfig
+	metadata    Metadata
+	anchor      string // in-book target set by LinkChapters
+	sections    int    // number of first subchapters which are parts of the content, set by SplitChapters
 }
 
 func NewEmptyChapter() chapter {
-	return chapter{"","", "", "", "", []chapter{}, NewScrapeConfigNoInclude()}
+	return chapter{"", "", "", "", "", []chapter{}, NewScrapeConfigNoInclude(), Metadata{}, "", 0}
 }
 
 func NewChapter(url, body, name, author, content string, subChapters []chapter, config *ScrapeConfig) chapter {
-	return chapter{url, body, name, author, content, subChapters, config}
+	return chapter{url, body, name, author, content, subChapters, config, Metadata{Canonical: url}, "", 0}
 }
 
 func (c chapter) Body() string {
@@ -34,12 +37,26 @@ func (c chapter) Author() string {
 	return c.author
 }
 
+func (c *chapter) SetAuthor(author string) {
+	c.author = author
+}
+
 func (c chapter) Url() string {
 	return c.url
 }
 
+// Canonical returns the URL declared by the page in <link rel=canonical>, or its own URL.
+func (c chapter) Canonical() string {
+	return c.metadata.Canonical
+}
+
+// Metadata returns the dates, language, site name, image and excerpt of the page
+func (c chapter) Metadata() Metadata {
+	return c.metadata
+}
+
 func (c chapter) Content() string {
-		return c.content
+	return c.content
 }
 
 func (c chapter) SubChapters() []chapter {
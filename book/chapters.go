@@ -0,0 +1,101 @@
+package book
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// chapterRange is an interval of chapter numbers starting at 1, negative numbers count from the last chapter
+type chapterRange struct {
+	start int
+	end   int // 0 means until the last chapter
+}
+
+var chapterRangeRegexp = regexp.MustCompile(`^(-?\d+)(-(-?\d+)?)?$`)
+
+// ParseChapters parses a chapter selection such as "1-5,8,20-" or "-3" for the last 3 chapters.
+func ParseChapters(s string) ([]chapterRange, error) {
+	ranges := []chapterRange{}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ReplaceAll(part, " ", "")
+		if len(part) == 0 {
+			continue
+		}
+
+		matches := chapterRangeRegexp.FindStringSubmatch(part)
+		if matches == nil {
+			return ranges, fmt.Errorf("invalid chapter range: %s", part)
+		}
+
+		start, _ := strconv.Atoi(matches[1])
+		if start == 0 {
+			return ranges, fmt.Errorf("invalid chapter range: %s, chapters start at 1", part)
+		}
+
+		switch {
+		case len(matches[2]) == 0 && start < 0:
+			// last chapters
+			ranges = append(ranges, chapterRange{start, 0})
+		case len(matches[2]) == 0:
+			// single chapter
+			ranges = append(ranges, chapterRange{start, start})
+		case len(matches[3]) == 0:
+			// open range
+			ranges = append(ranges, chapterRange{start, 0})
+		default:
+			end, _ := strconv.Atoi(matches[3])
+			if end == 0 {
+				return ranges, fmt.Errorf("invalid chapter range: %s, chapters start at 1", part)
+			}
+			ranges = append(ranges, chapterRange{start, end})
+		}
+	}
+
+	return ranges, nil
+}
+
+// selectChapters keeps the links matching the chapter selection, in their original order
+func selectChapters(links []link, selection string) ([]link, error) {
+	ranges, err := ParseChapters(selection)
+	if err != nil {
+		return links, err
+	}
+	if len(ranges) == 0 {
+		return links, nil
+	}
+
+	// resolve a chapter number, negative numbers count from the end
+	position := func(number int) int {
+		if number < 0 {
+			return len(links) + 1 + number
+		}
+		return number
+	}
+
+	selected := make([]bool, len(links))
+	for _, r := range ranges {
+		start := position(r.start)
+		end := len(links)
+		if r.end != 0 {
+			end = position(r.end)
+		}
+
+		for number := start; number <= end; number++ {
+			if number >= 1 && number <= len(links) {
+				selected[number-1] = true
+			}
+		}
+	}
+
+	result := []link{}
+	for index, l := range links {
+		if selected[index] {
+			result = append(result, l)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package book
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSelectChapters(t *testing.T) {
+
+	links := []link{}
+	for i := 1; i <= 25; i++ {
+		links = append(links, NewLink(fmt.Sprintf("https://example.com/%d", i), fmt.Sprint(i), &time.Time{}))
+	}
+
+	tests := map[string]string{
+		"1-3,8,23-": "[1 2 3 8 23 24 25]",
+		"-2":        "[24 25]",
+		"-4--3":     "[22 23]",
+		"24-30":     "[24 25]",
+		"":          fmt.Sprint(links),
+	}
+
+	for selection, want := range tests {
+		selected, err := selectChapters(links, selection)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		texts := []string{}
+		for _, l := range selected {
+			texts = append(texts, l.Text)
+		}
+
+		got := fmt.Sprint(texts)
+		if selection == "" {
+			got = fmt.Sprint(selected)
+		}
+
+		if got != want {
+			t.Errorf("got %v, wanted %v for %v", got, want, selection)
+		}
+	}
+
+}
+
+func TestParseChaptersInvalid(t *testing.T) {
+
+	for _, selection := range []string{"0", "a-b", "1-0", "1--"} {
+		if _, err := ParseChapters(selection); err == nil {
+			t.Errorf("wanted an error for %v", selection)
+		}
+	}
+
+}
@@ -0,0 +1,125 @@
+package book
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LinkStatus is the result of a request to a link of a table of contents
+type LinkStatus struct {
+	Code        int    `json:"code"`
+	FinalURL    string `json:"final_url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"` // -1 if unknown
+	Error       string `json:"error,omitempty"`
+}
+
+// Problem returns why the link cannot be scraped, or an empty string
+func (s LinkStatus) Problem() string {
+	if len(s.Error) > 0 {
+		return s.Error
+	}
+
+	if s.Code < 200 || s.Code >= 300 {
+		return fmt.Sprintf("HTTP %d", s.Code)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(s.ContentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "not HTML"
+	}
+
+	return ""
+}
+
+// timeout of each check request
+const checkTimeout = 30 * time.Second
+
+// CheckLink issues a HEAD request to url, falling back to GET for servers that do not support it
+func CheckLink(client *http.Client, url string) LinkStatus {
+	response, err := client.Head(url)
+	if err == nil && response.StatusCode < 400 {
+		response.Body.Close()
+		return newLinkStatus(response, response.ContentLength)
+	}
+	if err == nil {
+		response.Body.Close()
+	}
+
+	response, err = client.Get(url)
+	if err != nil {
+		return LinkStatus{0, url, "", -1, err.Error()}
+	}
+	defer response.Body.Close()
+
+	// the body is downloaded anyway, count it when the size is not announced
+	size := response.ContentLength
+	if size < 0 {
+		size, err = io.Copy(io.Discard, response.Body)
+		if err != nil {
+			size = -1
+		}
+	}
+
+	return newLinkStatus(response, size)
+}
+
+func newLinkStatus(response *http.Response, size int64) LinkStatus {
+	return LinkStatus{response.StatusCode, response.Request.URL.String(), response.Header.Get("Content-Type"), size, ""}
+}
+
+// CheckLinkTree sets the status of every node, each level respecting the delay and threads of its config
+func CheckLinkTree(nodes []LinkNode, configs []*ScrapeConfig) {
+	client := &http.Client{Timeout: checkTimeout}
+	checkLinkTreeLevel(client, nodes, configs)
+}
+
+func checkLinkTreeLevel(client *http.Client, nodes []LinkNode, configs []*ScrapeConfig) {
+	config := configs[0]
+
+	if config.Delay >= 0 {
+
+		// synchronous mode
+		for index := range nodes {
+			status := CheckLink(client, nodes[index].Href)
+			nodes[index].Status = &status
+
+			time.Sleep(time.Duration(config.Delay) * time.Millisecond)
+		}
+
+	} else {
+		// asynchronous mode
+		var wg sync.WaitGroup
+
+		threads := config.Threads
+		if threads == -1 {
+			threads = len(nodes)
+		}
+		semaphore := make(chan bool, threads)
+
+		for index := range nodes {
+			wg.Add(1)
+			semaphore <- true
+
+			go func(index int) {
+				defer wg.Done()
+
+				status := CheckLink(client, nodes[index].Href)
+				nodes[index].Status = &status
+
+				<-semaphore
+			}(index)
+		}
+		wg.Wait()
+	}
+
+	for _, node := range nodes {
+		if len(node.Chapters) > 0 && len(configs) > 1 {
+			checkLinkTreeLevel(client, node.Chapters, configs[1:])
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckLinkTree(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html></html>")
+		case "/book.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF")
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	nodes := []LinkNode{}
+	for _, path := range []string{"/old", "/book.pdf", "/nohead", "/missing"} {
+		nodes = append(nodes, LinkNode{NewLink(server.URL+path, path, &time.Time{}), nil, nil})
+	}
+
+	config := NewScrapeConfig()
+	config.Threads = 2
+	CheckLinkTree(nodes, []*ScrapeConfig{config})
+
+	got := []string{}
+	for _, node := range nodes {
+		got = append(got, fmt.Sprintf("%d %s", node.Status.Code, node.Status.Problem()))
+	}
+	want := []string{"200 ", "200 not HTML", "200 ", "404 HTTP 404"}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	if nodes[0].Status.FinalURL != server.URL+"/new" {
+		t.Errorf("got %v, wanted %v", nodes[0].Status.FinalURL, server.URL+"/new")
+	}
+
+}
@@ -0,0 +1,218 @@
+package book
+
+import (
+	"log"
+	urllib "net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extensions of files that are not web pages, they are never crawled
+var crawlSkippedExtensions = map[string]bool{
+	".css": true, ".js": true, ".json": true, ".xml": true, ".rss": true, ".txt": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".pdf": true, ".epub": true, ".mobi": true, ".zip": true, ".gz": true, ".tar": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".woff": true, ".woff2": true, ".ttf": true,
+}
+
+// NewChapterFromCrawl visits the pages of the website reachable from url breadth first, up to maxPages pages.
+// Only pages on the same host and under the directory of url are visited.
+// Chapters are nested following the URL path hierarchy: /guide/install is a subchapter of /guide.
+func NewChapterFromCrawl(url string, config *ScrapeConfig, maxPages int) chapter {
+	base, err := urllib.Parse(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// every page under the directory of the first page is part of the website
+	scope := base.Path
+	if strings.HasSuffix(scope, "/") == false {
+		scope = path.Dir(scope)
+	}
+	scope = strings.TrimSuffix(scope, "/") + "/"
+
+	// every page visited is included in the book
+	pageConfig := *config
+	pageConfig.Include = true
+
+	v := newVisited()
+	v.claim(url)
+
+	pages := []chapter{}
+	frontier := []link{NewLink(url, "", &time.Time{})}
+
+	for level := 0; len(frontier) > 0; level++ {
+
+		// init progess bar
+		var p progress
+		if config.Quiet == false {
+			p = NewProgress(frontier, base.Host, level)
+		}
+
+		chapters := make([]chapter, len(frontier))
+		claimed := make([]bool, len(frontier))
+
+		if config.Delay >= 0 {
+
+			// synchronous mode
+			for index, l := range frontier {
+				chapters[index], claimed[index] = newChapterFromURL(l.Href, l.Text, []*ScrapeConfig{&pageConfig}, index, p.UpdateName, v)
+				if config.Quiet == false {
+					p.Increment(index)
+				}
+
+				time.Sleep(time.Duration(config.Delay) * time.Millisecond)
+			}
+
+		} else {
+			// asynchronous mode
+			var wg sync.WaitGroup
+
+			threads := config.Threads
+			if threads == -1 {
+				threads = len(frontier)
+			}
+			semaphore := make(chan bool, threads)
+
+			for index, l := range frontier {
+
+				wg.Add(1)
+				semaphore <- true
+
+				go func(index int, l link) {
+					defer wg.Done()
+
+					chapters[index], claimed[index] = newChapterFromURL(l.Href, l.Text, []*ScrapeConfig{&pageConfig}, index, p.UpdateName, v)
+					if config.Quiet == false {
+						p.Increment(index)
+					}
+
+					<-semaphore
+				}(index, l)
+			}
+			wg.Wait()
+		}
+
+		// different links may lead to a page already in the book
+		chapters = dropDuplicates(chapters, claimed, false)
+		pages = append(pages, chapters...)
+
+		// next level is made of the links found on this level
+		frontier = []link{}
+		for _, c := range chapters {
+			for _, l := range crawlLinks(c, base.Host, scope) {
+				if len(pages)+len(frontier) >= maxPages {
+					break
+				}
+
+				if v.claim(l.Href) {
+					frontier = append(frontier, l)
+				}
+			}
+		}
+	}
+
+	// the script may modify or drop pages, except the root one
+	root := pages[0]
+	if transformed := transformChapters([]chapter{root}); len(transformed) > 0 {
+		root = transformed[0]
+	}
+
+	return nestChapters(append([]chapter{root}, transformChapters(pages[1:])...))
+}
+
+// crawlLinks returns the links of the chapter page pointing to pages of the website
+func crawlLinks(c chapter, host, scope string) []link {
+	links := []link{}
+
+	base, err := urllib.Parse(c.Url())
+	if err != nil {
+		return links
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(c.Body()))
+	if err != nil {
+		return links
+	}
+
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+
+		u, err := base.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		u = CleanURL(u)
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return
+		}
+		if u.Host != host || strings.HasPrefix(u.Path+"/", scope) == false {
+			return
+		}
+		if crawlSkippedExtensions[strings.ToLower(path.Ext(u.Path))] {
+			return
+		}
+
+		links = append(links, NewLink(u.String(), strings.TrimSpace(s.Text()), &time.Time{}))
+	})
+
+	return links
+}
+
+// pathKey returns the URL path used to nest chapters, without trailing slash and index page
+func pathKey(url string) string {
+	u, err := urllib.Parse(url)
+	if err != nil {
+		return url
+	}
+
+	key := strings.TrimSuffix(u.Path, "/")
+	for _, index := range []string{"/index.html", "/index.htm"} {
+		key = strings.TrimSuffix(key, index)
+	}
+
+	return key
+}
+
+// nestChapters turns the list of crawled pages into a tree following their URL path, the first page is the root
+func nestChapters(pages []chapter) chapter {
+	keys := map[string]int{}
+	for index, c := range pages {
+		key := pathKey(c.Url())
+		if _, exists := keys[key]; exists == false {
+			keys[key] = index
+		}
+	}
+
+	// attach every page to its closest ancestor, or to the root page
+	children := make([][]int, len(pages))
+	for index := 1; index < len(pages); index++ {
+		parent := 0
+
+		key := pathKey(pages[index].Url())
+		for ancestor := path.Dir(key); ancestor != "/" && ancestor != "." && len(ancestor) > 0; ancestor = path.Dir(ancestor) {
+			if i, exists := keys[ancestor]; exists && i != index {
+				parent = i
+				break
+			}
+		}
+
+		children[parent] = append(children[parent], index)
+	}
+
+	var nest func(index int) chapter
+	nest = func(index int) chapter {
+		c := pages[index]
+		for _, child := range children[index] {
+			c.AddSubChapter(nest(child))
+		}
+		return c
+	}
+
+	return nest(0)
+}
@@ -0,0 +1,82 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewChapterFromCrawl(t *testing.T) {
+
+	pages := map[string]string{
+		"/docs/":                `<a href="/docs/guide/install">Install</a> <a href="guide">Guide</a> <a href="/blog">Blog</a> <a href="manual.pdf">PDF</a>`,
+		"/docs/guide":           `<a href="/docs/">Home</a> <a href="/docs/guide/configure#top">Configure</a>`,
+		"/docs/guide/install":   `<a href="https://example.com/docs/other">External</a>`,
+		"/docs/guide/configure": `<p>Configure</p>`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, exists := pages[r.URL.Path]
+		if exists == false {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body>%s</body></html>", r.URL.Path, body)
+	}))
+	defer server.Close()
+
+	config := NewScrapeConfig()
+	config.Quiet = true
+
+	c := NewChapterFromCrawl(server.URL+"/docs/", config, 10)
+
+	if len(c.SubChapters()) != 1 {
+		t.Fatalf("got %v subchapters, wanted %v", len(c.SubChapters()), 1)
+	}
+
+	guide := c.SubChapters()[0]
+	if guide.Url() != server.URL+"/docs/guide" {
+		t.Errorf("got %v, wanted %v", guide.Url(), server.URL+"/docs/guide")
+	}
+
+	got := len(guide.SubChapters())
+	want := 2
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestNewChapterFromCrawlMaxPages(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><a href="%s/next">Next</a></body></html>`, strings.TrimSuffix(r.URL.Path, "/"))
+	}))
+	defer server.Close()
+
+	config := NewScrapeConfig()
+	config.Quiet = true
+
+	c := NewChapterFromCrawl(server.URL+"/", config, 3)
+
+	count := 0
+	var walk func(c chapter)
+	walk = func(c chapter) {
+		count++
+		for _, sc := range c.SubChapters() {
+			walk(sc)
+		}
+	}
+	walk(c)
+
+	if count != 3 {
+		t.Errorf("got %v, wanted %v", count, 3)
+	}
+
+}
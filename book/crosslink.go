@@ -0,0 +1,207 @@
+package book
+
+import (
+	"fmt"
+	"log"
+	urllib "net/url"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// LinkChapters gives an anchor to every included chapter of the book and rewrites the links between chapters
+// to in-book targets of format: XHTML files for epub and mobi, anchors for html, headers or files for md and json.
+// Links to pages outside of the book are left untouched.
+func LinkChapters(c chapter, format string) chapter {
+	n := 0
+	c = anchorChapters(c, &n)
+
+	targets := map[string]chapter{}
+	collectTargets(c, targets)
+
+	return linkChapters(c, targets, format)
+}
+
+// anchorChapters numbers the included chapters in reading order
+func anchorChapters(c chapter, n *int) chapter {
+	if c.config.Include && len(c.url) > 0 {
+		*n += 1
+		c.anchor = fmt.Sprintf("chapter%04d", *n)
+	}
+
+	subChapters := make([]chapter, len(c.subChapters))
+	for index, sc := range c.subChapters {
+		subChapters[index] = anchorChapters(sc, n)
+	}
+	c.subChapters = subChapters
+
+	return c
+}
+
+// collectTargets maps the URL and canonical URL of every included page to its chapter, the first one wins.
+// The ids of the content are mapped as URL#id, so a link to a part of a split page leads to the section holding it.
+// Cross references have no body, they point to the page elsewhere in the book.
+func collectTargets(c chapter, targets map[string]chapter) {
+	if len(c.anchor) > 0 && len(c.body) > 0 {
+		keys := []string{}
+		for _, u := range []string{c.url, c.Canonical()} {
+			keys = append(keys, NormalizeHref(u))
+			for _, id := range contentIDs(c.content) {
+				keys = append(keys, NormalizeHref(u)+"#"+id)
+			}
+		}
+
+		for _, key := range keys {
+			if _, exists := targets[key]; exists == false {
+				targets[key] = c
+			}
+		}
+	}
+
+	for _, sc := range c.subChapters {
+		collectTargets(sc, targets)
+	}
+}
+
+func linkChapters(c chapter, targets map[string]chapter, format string) chapter {
+	if len(c.content) > 0 {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(c.content))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		rewritten := false
+		doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+			href := strings.TrimSpace(s.AttrOr("href", ""))
+			u, err := urllib.Parse(href)
+			if err != nil {
+				return
+			}
+
+			var target chapter
+			exists := false
+			switch {
+			case u.IsAbs():
+				target, exists = targets[NormalizeURL(u)+"#"+u.Fragment]
+				if exists == false {
+					target, exists = targets[NormalizeURL(u)]
+				}
+
+			case strings.HasPrefix(href, "#") && len(u.Fragment) > 0 && len(c.url) > 0:
+				// a link inside a split page may lead to another section
+				target, exists = targets[NormalizeHref(c.url)+"#"+u.Fragment]
+				if target.anchor == c.anchor {
+					return
+				}
+			}
+			if exists == false {
+				return
+			}
+
+			s.SetAttr("href", chapterHref(c, target, u.Fragment, format))
+			rewritten = true
+		})
+
+		if rewritten {
+			content, err := doc.Find("body").Html()
+			if err != nil {
+				log.Fatal(err)
+			}
+			c.content = content
+		}
+	}
+
+	subChapters := make([]chapter, len(c.subChapters))
+	for index, sc := range c.subChapters {
+		subChapters[index] = linkChapters(sc, targets, format)
+	}
+	c.subChapters = subChapters
+
+	return c
+}
+
+// contentIDs returns the ids of the elements of content
+func contentIDs(content string) []string {
+	ids := []string{}
+	if strings.Contains(content, "id=") == false {
+		return ids
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	doc.Find("body [id]").Each(func(i int, s *goquery.Selection) {
+		ids = append(ids, s.AttrOr("id", ""))
+	})
+
+	return ids
+}
+
+// chapterHref returns the link from chapter c to target in format
+func chapterHref(c, target chapter, fragment string, format string) string {
+	if len(fragment) > 0 {
+		fragment = "#" + fragment
+	}
+
+	switch format {
+	case "epub", "mobi":
+		return epubFilename(target) + fragment
+
+	case "md", "json":
+		if c.config.SeparateMarkdown == false {
+			return "#" + markdownSlug(target.Name())
+		}
+
+		// separate files are saved in a directory named after the host
+		filename := fmt.Sprintf("%s.md", Filename(target.Name()))
+		if hostname(c.url) != hostname(target.url) {
+			filename = fmt.Sprintf("../%s/%s", hostname(target.url), filename)
+		}
+		return filename + fragment
+	}
+
+	// chapters share one document, the fragment is kept when the target holds it
+	for _, id := range contentIDs(target.content) {
+		if fragment == "#"+id {
+			return fragment
+		}
+	}
+
+	return "#" + target.anchor
+}
+
+// epubFilename returns the internal file name of the chapter in the EPUB, empty if it has no anchor
+func epubFilename(c chapter) string {
+	if len(c.anchor) == 0 {
+		return ""
+	}
+
+	return c.anchor + ".xhtml"
+}
+
+// markdownSlug returns the anchor of a Markdown header, like GitHub generates it
+func markdownSlug(name string) string {
+	slug := strings.Builder{}
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			slug.WriteRune(r)
+		case r == ' ':
+			slug.WriteRune('-')
+		}
+	}
+
+	return slug.String()
+}
+
+func hostname(link string) string {
+	u, err := urllib.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
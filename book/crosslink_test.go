@@ -0,0 +1,88 @@
+package book
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func crossLinkedBook() chapter {
+	config := NewScrapeConfig()
+
+	first := NewChapter("https://example.com/first", "<html></html>", "First Page", "", `<p><a href="https://example.com/second/#part">second</a> <a href="https://example.com/second#gone">gone</a> <a href="https://example.org/">outside</a></p>`, []chapter{}, config)
+	second := NewChapter("https://example.com/second", "<html></html>", "Second Page", "", `<p id="part"><a href="https://EXAMPLE.com/first?utm_source=feed">first</a></p>`, []chapter{}, config)
+	reference := newCrossReference("https://example.com/first", "First Page", config)
+
+	c := NewEmptyChapter()
+	c.AddSubChapter(first)
+	c.AddSubChapter(second)
+	c.AddSubChapter(reference)
+
+	return c
+}
+
+func TestLinkChapters(t *testing.T) {
+
+	for _, test := range []struct {
+		format string
+		want   []string
+	}{
+		{"html", []string{`href="#part"`, `href="#chapter0002"`, `href="https://example.org/"`, `href="#chapter0001"`, `<h1 id="chapter0002">Second Page</h1>`}},
+		{"epub", []string{`href="chapter0002.xhtml#part"`, `href="chapter0001.xhtml"`}},
+		{"md", []string{"[second](#second-page)", "[outside](https://example.org/)", "[first](#first-page)"}},
+	} {
+		c := LinkChapters(crossLinkedBook(), test.format)
+
+		got := ""
+		for _, sc := range c.SubChapters() {
+			got += sc.Content()
+		}
+		if test.format == "html" {
+			got = ToHtmlString(c)
+		}
+		if test.format == "md" {
+			got = ToMarkdownString(c)
+		}
+
+		for _, want := range test.want {
+			if strings.Contains(got, want) == false {
+				t.Errorf("%s: got %v, wanted %v", test.format, got, want)
+			}
+		}
+	}
+
+}
+
+func TestLinkChaptersSeparateMarkdown(t *testing.T) {
+
+	c := crossLinkedBook()
+	for _, sc := range c.SubChapters() {
+		sc.config.SeparateMarkdown = true
+	}
+
+	c = LinkChapters(c, "md")
+
+	got := c.SubChapters()[0].Content()
+	want := `href="Second_Page.md#part"`
+
+	if strings.Contains(got, want) == false {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestLinkChaptersEpub(t *testing.T) {
+
+	c := LinkChapters(crossLinkedBook(), "epub")
+	c.SetName("Book")
+
+	filename := filepath.Join(t.TempDir(), "book.epub")
+
+	got := ToEpub(c, filename)
+	want := filename
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
@@ -0,0 +1,184 @@
+package book
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	urllib "net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	readability "github.com/go-shiori/go-readability"
+)
+
+// below this number of characters, readability most likely got the empty shell of a javascript website
+const embeddedMinLength = 500
+
+// keys holding the article in framework data, their values are used even without HTML tags
+var embeddedContentKeys = map[string]bool{
+	"articleBody": true,
+	"body":        true,
+	"bodyHtml":    true,
+	"content":     true,
+	"contentHtml": true,
+	"html":        true,
+	"text":        true,
+}
+
+var htmlTagRegexp = regexp.MustCompile(`<(p|div|h[1-6]|br|ul|ol|li|blockquote|pre|figure|img)[\s/>]`)
+
+// embeddedArticle replaces the article content with the one embedded in JSON-LD, Next.js or Nuxt data
+// when readability extracted too little text from the page
+func embeddedArticle(base *urllib.URL, page *goquery.Document, article readability.Article) readability.Article {
+	length := utf8.RuneCountInString(strings.TrimSpace(article.TextContent))
+	if length >= embeddedMinLength {
+		return article
+	}
+
+	title, content := embeddedContent(page)
+	if len(content) == 0 {
+		return article
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return article
+	}
+	doc.Find("script, style, iframe, object, embed").Remove()
+	absoluteURLs(base, doc.Selection)
+
+	text := strings.TrimSpace(doc.Text())
+	if utf8.RuneCountInString(text) <= length {
+		return article
+	}
+
+	body, err := doc.Find("body").Html()
+	if err != nil {
+		return article
+	}
+
+	article.Content = fmt.Sprintf(`<div id="readability-page-1" class="page">%s</div>`, body)
+	article.TextContent = text
+	article.Length = utf8.RuneCountInString(text)
+	if len(strings.TrimSpace(article.Title)) == 0 {
+		article.Title = title
+	}
+
+	return article
+}
+
+// embeddedContent returns the title and HTML content of the article found in the page data, JSON-LD first
+func embeddedContent(page *goquery.Document) (string, string) {
+	title, content := "", ""
+
+	// schema.org article
+	page.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var data interface{}
+		if json.Unmarshal([]byte(s.Text()), &data) != nil {
+			return true
+		}
+
+		title, content = jsonLDArticle(data)
+		return len(content) == 0
+	})
+	if len(content) > 0 {
+		return title, textToHTML(content)
+	}
+
+	// Next.js and Nuxt 3 page data, Nuxt 2 state when it is plain JSON
+	for _, selector := range []string{"script#__NEXT_DATA__", "script#__NUXT_DATA__", "script"} {
+		page.Find(selector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+			raw := strings.TrimSpace(s.Text())
+			if selector == "script" {
+				if strings.HasPrefix(raw, "window.__NUXT__=") == false {
+					return true
+				}
+				raw = strings.TrimSuffix(strings.TrimPrefix(raw, "window.__NUXT__="), ";")
+			}
+
+			var data interface{}
+			if json.Unmarshal([]byte(raw), &data) != nil {
+				return true
+			}
+
+			content = longestContent(data, "")
+			return len(content) == 0
+		})
+
+		if len(content) > 0 {
+			return "", textToHTML(content)
+		}
+	}
+
+	return "", ""
+}
+
+// jsonLDArticle returns the headline and body of the first JSON-LD object with an articleBody
+func jsonLDArticle(data interface{}) (string, string) {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if title, body := jsonLDArticle(item); len(body) > 0 {
+				return title, body
+			}
+		}
+
+	case map[string]interface{}:
+		if body, ok := v["articleBody"].(string); ok && len(strings.TrimSpace(body)) > 0 {
+			title, _ := v["headline"].(string)
+			return title, body
+		}
+
+		if graph, exists := v["@graph"]; exists {
+			return jsonLDArticle(graph)
+		}
+	}
+
+	return "", ""
+}
+
+// longestContent returns the longest string of data which contains HTML or is stored under a content key
+func longestContent(data interface{}, key string) string {
+	longest := ""
+
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s := longestContent(item, key); len(s) > len(longest) {
+				longest = s
+			}
+		}
+
+	case map[string]interface{}:
+		for k, item := range v {
+			if s := longestContent(item, k); len(s) > len(longest) {
+				longest = s
+			}
+		}
+
+	case string:
+		if embeddedContentKeys[key] || htmlTagRegexp.MatchString(v) {
+			longest = v
+		}
+	}
+
+	return longest
+}
+
+// textToHTML returns s if it is HTML, otherwise turns every paragraph of the text into a <p> element
+func textToHTML(s string) string {
+	if htmlTagRegexp.MatchString(s) {
+		return s
+	}
+
+	content := ""
+	for _, paragraph := range strings.Split(s, "\n") {
+		if paragraph = strings.TrimSpace(paragraph); len(paragraph) > 0 {
+			content += "<p>" + html.EscapeString(paragraph) + "</p>"
+		}
+	}
+
+	return content
+}
@@ -0,0 +1,44 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedContent(t *testing.T) {
+
+	paragraph := strings.Repeat("This sentence is part of the embedded article. ", 20)
+
+	pages := map[string]string{
+		"/next": fmt.Sprintf(`<script id="__NEXT_DATA__" type="application/json">{"props": {"pageProps": {"post": {"title": "Post", "content": "<p>%s</p><img src=\"/image.png\">"}}}}</script>`, paragraph),
+		"/nuxt": fmt.Sprintf(`<script>window.__NUXT__={"data": [{"article": {"body": "%s\n%s"}}]};</script>`, paragraph, paragraph),
+		"/ld":   fmt.Sprintf(`<script type="application/ld+json">{"@graph": [{"@type": "WebPage"}, {"@type": "Article", "headline": "Post", "articleBody": "%s"}]}</script>`, paragraph),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>App</title>%s</head><body><div id="root">Loading...</div></body></html>`, pages[r.URL.Path])
+	}))
+	defer server.Close()
+
+	for path := range pages {
+		c := NewChapterFromURL(server.URL+path, "", []*ScrapeConfig{NewScrapeConfig()}, 0, func(index int, name string) {})
+
+		if strings.Contains(c.Content(), "This sentence is part of the embedded article.") == false {
+			t.Errorf("%s: got %v, wanted embedded article", path, c.Content())
+		}
+	}
+
+	c := NewChapterFromURL(server.URL+"/next", "", []*ScrapeConfig{NewScrapeConfig()}, 0, func(index int, name string) {})
+
+	got := strings.Contains(c.Content(), server.URL+"/image.png")
+	want := true
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", c.Content(), want)
+	}
+
+}
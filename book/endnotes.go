@@ -0,0 +1,191 @@
+package book
+
+import (
+	"fmt"
+	"html"
+	"log"
+	urllib "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// LinkStyles are the values accepted by StyleLinks
+var LinkStyles = []string{"inline", "endnotes", "strip"}
+
+// StyleLinks changes how the links to web pages are displayed, links inside the book are kept:
+// inline keeps them, strip keeps their text only and endnotes numbers them and lists their URLs
+// at the end of each chapter, or in a last chapter if bookEnd is set.
+// Endnotes and their references link to each other, except in md and json formats.
+func StyleLinks(c chapter, style string, bookEnd bool, format string) chapter {
+	switch style {
+	case "strip":
+		return styleLinks(c, func(s *goquery.Selection, u *urllib.URL) {
+			s.ReplaceWithSelection(s.Contents())
+		})
+
+	case "endnotes":
+		notes := newEndnotes("")
+		chapters := 0
+		c = endnoteLinks(c, notes, bookEnd, format, &chapters)
+
+		if bookEnd && len(notes.urls) > 0 {
+			config := *NewScrapeConfig()
+			c.AddSubChapter(chapter{"", "", "Links", "", notes.Html(format), []chapter{}, &config, Metadata{}, endnotesAnchor, 0})
+		}
+	}
+
+	return c
+}
+
+// anchor of the last chapter listing the endnotes of the book
+const endnotesAnchor = "endnotes"
+
+// endnotes are the URLs referenced by a chapter, or by the whole book
+type endnotes struct {
+	prefix    string
+	urls      []string
+	numbers   map[string]int
+	backlinks []string
+}
+
+// newEndnotes returns an empty list of notes, prefix keeps their ids unique in the book
+func newEndnotes(prefix string) *endnotes {
+	return &endnotes{prefix, []string{}, map[string]int{}, []string{}}
+}
+
+// number returns the note number of url and whether it is a new note, a URL referenced several times keeps its number
+func (n *endnotes) number(url string) (int, bool) {
+	if number, exists := n.numbers[url]; exists {
+		return number, false
+	}
+
+	n.urls = append(n.urls, url)
+	n.backlinks = append(n.backlinks, "")
+	n.numbers[url] = len(n.urls)
+
+	return len(n.urls), true
+}
+
+// id returns the id of note number
+func (n *endnotes) id(number int) string {
+	return fmt.Sprintf("endnote-%s%d", n.prefix, number)
+}
+
+// refID returns the id of the first reference to note number
+func (n *endnotes) refID(number int) string {
+	return fmt.Sprintf("endnote-ref-%s%d", n.prefix, number)
+}
+
+// Html returns the numbered list of URLs
+func (n *endnotes) Html(format string) string {
+	list := `<ol class="endnotes">`
+	for index, url := range n.urls {
+		if linkedEndnotes(format) == false {
+			list += fmt.Sprintf(`<li><a href="%s">%s</a></li>`, html.EscapeString(url), html.EscapeString(url))
+			continue
+		}
+
+		list += fmt.Sprintf(`<li id="%s"><a href="%s">%s</a> <a href="%s">↩</a></li>`, n.id(index+1), html.EscapeString(url), html.EscapeString(url), html.EscapeString(n.backlinks[index]))
+	}
+	list += "</ol>"
+
+	return list
+}
+
+// linkedEndnotes tells if notes and references of format link to each other, Markdown drops their ids
+func linkedEndnotes(format string) bool {
+	return format != "md" && format != "json"
+}
+
+func endnoteLinks(c chapter, notes *endnotes, bookEnd bool, format string, chapters *int) chapter {
+	*chapters += 1
+
+	chapterNotes := notes
+	notesFile := ""
+	chapterFile := ""
+	if bookEnd {
+		// the notes are in their own file in EPUB books
+		if format == "epub" || format == "mobi" {
+			notesFile = endnotesAnchor + ".xhtml"
+			chapterFile = epubFilename(c)
+		}
+	} else {
+		chapterNotes = newEndnotes(fmt.Sprintf("%d-", *chapters))
+	}
+
+	c = styleChapterLinks(c, func(s *goquery.Selection, u *urllib.URL) {
+		// the note number follows the link text
+		number, first := chapterNotes.number(u.String())
+
+		switch {
+		case linkedEndnotes(format) == false:
+			s.AfterHtml(fmt.Sprintf("<sup>[%d]</sup>", number))
+		case first:
+			// the note links back to its first reference
+			chapterNotes.backlinks[number-1] = chapterFile + "#" + chapterNotes.refID(number)
+			s.AfterHtml(fmt.Sprintf(`<sup id="%s"><a href="%s#%s">[%d]</a></sup>`, chapterNotes.refID(number), notesFile, chapterNotes.id(number), number))
+		default:
+			s.AfterHtml(fmt.Sprintf(`<sup><a href="%s#%s">[%d]</a></sup>`, notesFile, chapterNotes.id(number), number))
+		}
+		s.ReplaceWithSelection(s.Contents())
+	})
+
+	if bookEnd == false && len(chapterNotes.urls) > 0 {
+		c.content += "<h2>Links</h2>" + chapterNotes.Html(format)
+	}
+
+	subChapters := make([]chapter, len(c.subChapters))
+	for index, sc := range c.subChapters {
+		subChapters[index] = endnoteLinks(sc, notes, bookEnd, format, chapters)
+	}
+	c.subChapters = subChapters
+
+	return c
+}
+
+// styleLinks applies style to the web links of every chapter
+func styleLinks(c chapter, style func(s *goquery.Selection, u *urllib.URL)) chapter {
+	c = styleChapterLinks(c, style)
+
+	subChapters := make([]chapter, len(c.subChapters))
+	for index, sc := range c.subChapters {
+		subChapters[index] = styleLinks(sc, style)
+	}
+	c.subChapters = subChapters
+
+	return c
+}
+
+// styleChapterLinks applies style to the links of the chapter content pointing to web pages
+func styleChapterLinks(c chapter, style func(s *goquery.Selection, u *urllib.URL)) chapter {
+	if len(c.content) == 0 || c.config.ImagesOnly {
+		return c
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(c.content))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	styled := false
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		u, err := urllib.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+
+		style(s, u)
+		styled = true
+	})
+
+	if styled {
+		content, err := doc.Find("body").Html()
+		if err != nil {
+			log.Fatal(err)
+		}
+		c.content = content
+	}
+
+	return c
+}
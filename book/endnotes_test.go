@@ -0,0 +1,48 @@
+package book
+
+import (
+	"strings"
+	"testing"
+)
+
+func linkedBook() chapter {
+	config := NewScrapeConfig()
+
+	c := NewEmptyChapter()
+	c.AddSubChapter(NewChapter("https://example.com/1", "", "One", "", `<p>Read <a href="https://example.org/a">this</a>, <a href="#top">top</a> and <a href="https://example.org/a">this again</a>.</p>`, []chapter{}, config))
+	c.AddSubChapter(NewChapter("https://example.com/2", "", "Two", "", `<p>See <a href="https://example.org/b"><em>that</em></a>.</p>`, []chapter{}, config))
+
+	return c
+}
+
+func TestStyleLinks(t *testing.T) {
+
+	for _, test := range []struct {
+		style   string
+		bookEnd bool
+		format  string
+		want    string
+	}{
+		{"inline", false, "md", `<p>Read <a href="https://example.org/a">this</a>, <a href="#top">top</a> and <a href="https://example.org/a">this again</a>.</p>|<p>See <a href="https://example.org/b"><em>that</em></a>.</p>`},
+		{"strip", false, "md", `<p>Read this, <a href="#top">top</a> and this again.</p>|<p>See <em>that</em>.</p>`},
+		{"endnotes", false, "md", `<p>Read this<sup>[1]</sup>, <a href="#top">top</a> and this again<sup>[1]</sup>.</p><h2>Links</h2><ol class="endnotes"><li><a href="https://example.org/a">https://example.org/a</a></li></ol>|<p>See <em>that</em><sup>[1]</sup>.</p><h2>Links</h2><ol class="endnotes"><li><a href="https://example.org/b">https://example.org/b</a></li></ol>`},
+		{"endnotes", true, "md", `<p>Read this<sup>[1]</sup>, <a href="#top">top</a> and this again<sup>[1]</sup>.</p>|<p>See <em>that</em><sup>[2]</sup>.</p>|<ol class="endnotes"><li><a href="https://example.org/a">https://example.org/a</a></li><li><a href="https://example.org/b">https://example.org/b</a></li></ol>`},
+		// notes and references link to each other
+		{"endnotes", false, "html", `<p>Read this<sup id="endnote-ref-2-1"><a href="#endnote-2-1">[1]</a></sup>, <a href="#top">top</a> and this again<sup><a href="#endnote-2-1">[1]</a></sup>.</p><h2>Links</h2><ol class="endnotes"><li id="endnote-2-1"><a href="https://example.org/a">https://example.org/a</a> <a href="#endnote-ref-2-1">↩</a></li></ol>|<p>See <em>that</em><sup id="endnote-ref-3-1"><a href="#endnote-3-1">[1]</a></sup>.</p><h2>Links</h2><ol class="endnotes"><li id="endnote-3-1"><a href="https://example.org/b">https://example.org/b</a> <a href="#endnote-ref-3-1">↩</a></li></ol>`},
+		{"endnotes", true, "epub", `<p>Read this<sup id="endnote-ref-1"><a href="endnotes.xhtml#endnote-1">[1]</a></sup>, <a href="#top">top</a> and this again<sup><a href="endnotes.xhtml#endnote-1">[1]</a></sup>.</p>|<p>See <em>that</em><sup id="endnote-ref-2"><a href="endnotes.xhtml#endnote-2">[2]</a></sup>.</p>|<ol class="endnotes"><li id="endnote-1"><a href="https://example.org/a">https://example.org/a</a> <a href="chapter0001.xhtml#endnote-ref-1">↩</a></li><li id="endnote-2"><a href="https://example.org/b">https://example.org/b</a> <a href="chapter0002.xhtml#endnote-ref-2">↩</a></li></ol>`},
+	} {
+		c := StyleLinks(LinkChapters(linkedBook(), test.format), test.style, test.bookEnd, test.format)
+
+		contents := []string{}
+		for _, sc := range c.SubChapters() {
+			contents = append(contents, sc.Content())
+		}
+
+		got := strings.Join(contents, "|")
+
+		if got != test.want {
+			t.Errorf("%s %s: got %v, wanted %v", test.style, test.format, got, test.want)
+		}
+	}
+
+}
@@ -0,0 +1,99 @@
+package book
+
+import (
+	"fmt"
+	"log"
+	urllib "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	readability "github.com/go-shiori/go-readability"
+)
+
+// Extractors are the names accepted by NewExtractor
+var Extractors = []string{"readability", "selector", "body"}
+
+// Extractor returns the HTML content of a page, wrapped in a readability page element
+// so every extractor goes through the same images handling.
+// article is the readability result of the page, which is always computed for the chapter metadata.
+type Extractor interface {
+	Extract(base *urllib.URL, page *goquery.Document, article readability.Article) (string, error)
+}
+
+// NewExtractor returns the extractor called name, selectors are used by the selector extractor only
+func NewExtractor(name string, selectors []string) (Extractor, error) {
+	switch name {
+	case "readability", "body":
+		if len(selectors) > 0 {
+			return nil, fmt.Errorf("cannot use content selectors with %s extractor", name)
+		}
+
+		if name == "body" {
+			return BodyExtractor{}, nil
+		}
+		return ReadabilityExtractor{}, nil
+
+	case "selector":
+		if len(selectors) == 0 {
+			return nil, fmt.Errorf("selector extractor requires a content selector")
+		}
+
+		return SelectorExtractor{selectors}, nil
+	}
+
+	return nil, fmt.Errorf("invalid extractor: %s, use one of %s", name, strings.Join(Extractors, ", "))
+}
+
+// ReadabilityExtractor keeps the main content guessed by readability, this is the default extractor
+type ReadabilityExtractor struct{}
+
+func (e ReadabilityExtractor) Extract(base *urllib.URL, page *goquery.Document, article readability.Article) (string, error) {
+	return article.Content, nil
+}
+
+// SelectorExtractor keeps the elements matching one of the CSS selectors, in document order.
+// The readability content is used if no element matches.
+type SelectorExtractor struct {
+	Selectors []string
+}
+
+func (e SelectorExtractor) Extract(base *urllib.URL, page *goquery.Document, article readability.Article) (string, error) {
+	content := ""
+	page.Find(strings.Join(e.Selectors, ", ")).Each(func(i int, s *goquery.Selection) {
+		s = s.Clone()
+		absoluteURLs(base, s)
+
+		element, err := goquery.OuterHtml(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		content += element
+	})
+
+	if len(content) == 0 {
+		return article.Content, nil
+	}
+
+	return wrapPage(content), nil
+}
+
+// BodyExtractor keeps the whole body of the page, without scripts and styles
+type BodyExtractor struct{}
+
+func (e BodyExtractor) Extract(base *urllib.URL, page *goquery.Document, article readability.Article) (string, error) {
+	body := page.Find("body").Clone()
+	body.Find("script, style, noscript, template").Remove()
+	absoluteURLs(base, body)
+
+	content, err := body.Html()
+	if err != nil {
+		return "", err
+	}
+
+	return wrapPage(content), nil
+}
+
+// wrapPage wraps content like readability does
+func wrapPage(content string) string {
+	return fmt.Sprintf(`<div id="readability-page-1" class="page">%s</div>`, content)
+}
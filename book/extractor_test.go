@@ -0,0 +1,59 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtractors(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Page</title></head><body>
+			<nav>Menu</nav>
+			<article><p>Article</p><pre class="code">code sample</pre></article>
+			<script>tracking()</script>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	tests := map[string][]string{
+		"body":     {"Menu", "Article", "code sample"},
+		"selector": {"code sample"},
+	}
+
+	for name, wants := range tests {
+		selectors := []string{}
+		if name == "selector" {
+			selectors = []string{"pre.code", "nav"}
+		}
+
+		extractor, err := NewExtractor(name, selectors)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config := NewScrapeConfig()
+		config.Extractor = extractor
+
+		c := NewChapterFromURL(server.URL, "", []*ScrapeConfig{config}, 0, func(index int, name string) {})
+
+		for _, want := range wants {
+			if strings.Contains(c.Content(), want) == false {
+				t.Errorf("%s: got %v, wanted %v", name, c.Content(), want)
+			}
+		}
+
+		if strings.Contains(c.Content(), "tracking") {
+			t.Errorf("%s: got %v, did not want scripts", name, c.Content())
+		}
+	}
+
+	if _, err := NewExtractor("selector", []string{}); err == nil {
+		t.Errorf("got no error, wanted an error for selector extractor without selector")
+	}
+
+}
@@ -0,0 +1,247 @@
+package book
+
+import (
+	"fmt"
+	"html"
+	"log"
+	urllib "net/url"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// elements which can hold a footnote, an anchor inside one of them is replaced by the element
+const footnoteContainers = "li, p, div, aside, dd, section"
+
+// markFootnotes finds the footnote references of the content and the notes they point to,
+// and marks them with the doc-noteref, doc-footnote and doc-backlink roles used by the formatters
+func markFootnotes(doc *goquery.Document, base *urllib.URL) {
+	doc.Find("a[href]").Each(func(i int, ref *goquery.Selection) {
+		id := pageFragment(base, ref.AttrOr("href", ""))
+		if len(id) == 0 || isNoteRef(ref) == false {
+			return
+		}
+
+		note := elementByID(doc, id)
+		if note.Length() == 0 || note.Has("h1, h2, h3, h4, h5, h6").Length() > 0 {
+			return
+		}
+
+		// the id is often set on an empty anchor at the beginning of the note
+		if strings.TrimSpace(note.Text()) == "" || goquery.NodeName(note) == "a" || goquery.NodeName(note) == "span" {
+			container := note.Closest(footnoteContainers)
+			if container.Length() == 0 {
+				return
+			}
+			if goquery.NodeName(note) == "a" && strings.TrimSpace(note.Text()) == "" {
+				note.Remove()
+			} else {
+				note.RemoveAttr("id")
+			}
+			note = container
+			note.SetAttr("id", id)
+		}
+
+		// a note containing the reference is a section of notes or the whole text
+		if note.Find("[role=doc-footnote]").Length() > 0 || note.Contains(ref.Get(0)) {
+			return
+		}
+
+		ref.SetAttr("href", "#"+id)
+		ref.SetAttr("role", "doc-noteref")
+		note.SetAttr("role", "doc-footnote")
+
+		// links back to the reference
+		refIDs := map[string]bool{}
+		for _, s := range []*goquery.Selection{ref, ref.Closest("sup")} {
+			if refID, exists := s.Attr("id"); exists {
+				refIDs[refID] = true
+			}
+		}
+		note.Find("a[href]").Each(func(i int, back *goquery.Selection) {
+			text := strings.TrimSpace(back.Text())
+			if refIDs[pageFragment(base, back.AttrOr("href", ""))] || text == "↩" || text == "↩︎" || text == "^" {
+				back.SetAttr("role", "doc-backlink")
+			}
+		})
+	})
+}
+
+// isNoteRef tells if the link looks like a footnote reference
+func isNoteRef(ref *goquery.Selection) bool {
+	class := strings.ToLower(ref.AttrOr("class", "") + " " + ref.Parent().AttrOr("class", ""))
+
+	return ref.AttrOr("role", "") == "doc-noteref" ||
+		ref.Closest("sup").Length() > 0 ||
+		ref.Find("sup").Length() > 0 ||
+		strings.Contains(class, "footnote") ||
+		strings.Contains(class, "noteref")
+}
+
+// pageFragment returns the fragment of href if it points to the page at base, or an empty string
+func pageFragment(base *urllib.URL, href string) string {
+	u, err := urllib.Parse(strings.TrimSpace(href))
+	if err != nil || len(u.Fragment) == 0 {
+		return ""
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(href), "#") == false && NormalizeURL(base.ResolveReference(u)) != NormalizeURL(base) {
+		return ""
+	}
+
+	return u.Fragment
+}
+
+// elementByID returns the first element of doc with id, which may contain characters reserved by CSS
+func elementByID(doc *goquery.Document, id string) *goquery.Selection {
+	return doc.Find("[id]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.AttrOr("id", "") == id
+	}).First()
+}
+
+// removeNotes removes the footnotes from the content and returns them by id, along with the ids in order of reference.
+// Backlinks and lists left empty are removed too.
+func removeNotes(doc *goquery.Document) ([]string, map[string]*goquery.Selection) {
+	ids := []string{}
+	notes := map[string]*goquery.Selection{}
+
+	doc.Find("a[role=doc-noteref]").Each(func(i int, ref *goquery.Selection) {
+		id := strings.TrimPrefix(ref.AttrOr("href", ""), "#")
+		if _, exists := notes[id]; exists {
+			return
+		}
+
+		note := elementByID(doc, id)
+		if note.Length() == 0 {
+			return
+		}
+
+		ids = append(ids, id)
+		notes[id] = note
+	})
+
+	for _, note := range notes {
+		parent := note.Parent()
+
+		note.Find("[role=doc-backlink]").Remove()
+		note.Remove()
+
+		// lists and sections of notes left empty
+		for parent.Length() > 0 && goquery.NodeName(parent) != "body" && strings.TrimSpace(parent.Text()) == "" && parent.Find("img").Length() == 0 {
+			next := parent.Parent()
+			parent.Remove()
+			parent = next
+		}
+	}
+
+	return ids, notes
+}
+
+// epubFootnotes turns the footnotes of content into EPUB3 notes, shown as popups by e-readers
+func epubFootnotes(content string) string {
+	if strings.Contains(content, "doc-noteref") == false {
+		return content
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ids, notes := removeNotes(doc)
+	doc.Find("a[role=doc-noteref]").SetAttr("epub:type", "noteref")
+
+	body := doc.Find("body")
+	for _, id := range ids {
+		note, err := notes[id].Html()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		body.AppendHtml(fmt.Sprintf(`<aside epub:type="footnote" role="doc-footnote" id="%s">%s</aside>`, html.EscapeString(id), note))
+	}
+
+	content, err = body.Html()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return content
+}
+
+// markdownFootnotes converts content to Markdown with [^n] footnotes, numbered after the first one of the file
+func markdownFootnotes(content string, first int) (string, int) {
+	converter := md.NewConverter("", true, nil)
+
+	if strings.Contains(content, "doc-noteref") == false {
+		markdown, err := converter.ConvertString(content)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return markdown, 0
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ids, notes := removeNotes(doc)
+	numbers := map[string]int{}
+	for index, id := range ids {
+		numbers[id] = first + index
+	}
+
+	converter.AddRules(md.Rule{
+		Filter: []string{"a"},
+		Replacement: func(content string, s *goquery.Selection, options *md.Options) *string {
+			number, exists := numbers[strings.TrimPrefix(s.AttrOr("href", ""), "#")]
+			if s.AttrOr("role", "") != "doc-noteref" || exists == false {
+				return nil
+			}
+
+			return md.String(fmt.Sprintf("[^%d]", number))
+		},
+	})
+	// the reference number is replaced by the footnote marker
+	converter.AddRules(md.Rule{
+		Filter: []string{"sup"},
+		Replacement: func(content string, s *goquery.Selection, options *md.Options) *string {
+			if s.Find("a[role=doc-noteref]").Length() == 0 {
+				return nil
+			}
+
+			return md.String(content)
+		},
+	})
+
+	markdown, err := converter.ConvertString(mustHtml(doc.Find("body")))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	markdown += "\n"
+	for _, id := range ids {
+		note, err := converter.ConvertString(mustHtml(notes[id]))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// continuation lines of a footnote are indented
+		note = strings.ReplaceAll(strings.TrimSpace(note), "\n", "\n    ")
+		markdown += fmt.Sprintf("\n[^%d]: %s", numbers[id], note)
+	}
+
+	return markdown, len(ids)
+}
+
+func mustHtml(s *goquery.Selection) string {
+	content, err := s.Html()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return content
+}
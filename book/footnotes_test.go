@@ -0,0 +1,80 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func footnotesServer() *httptest.Server {
+	paragraph := strings.Repeat("This sentence is part of the article. ", 20)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>Notes</title></head><body><article>
+			<p>%s First claim<sup id="fnref1"><a href="#fn1">1</a></sup> and second claim<sup class="reference"><a href="%s#cite_note-2">[2]</a></sup>, see <a href="#intro">intro</a>.</p>
+			<h2 id="intro">Intro</h2>
+			<p>%s</p>
+			<section class="footnotes"><ol>
+				<li id="fn1"><p>First note. <a href="#fnref1">↩</a></p></li>
+				<li><a id="cite_note-2"></a>Second <em>note</em>.</li>
+			</ol></section>
+		</article></body></html>`, paragraph, r.URL.Path, paragraph)
+	}))
+}
+
+func TestMarkFootnotes(t *testing.T) {
+
+	server := footnotesServer()
+	defer server.Close()
+
+	c := NewChapterFromURL(server.URL+"/post", "", []*ScrapeConfig{NewScrapeConfig()}, 0, func(index int, name string) {})
+
+	got := c.Content()
+	for _, want := range []string{`<a href="#fn1" role="doc-noteref">`, `<a href="#cite_note-2" role="doc-noteref">`, `id="fn1" role="doc-footnote"`, `id="cite_note-2" role="doc-footnote"`, `role="doc-backlink"`} {
+		if strings.Contains(got, want) == false {
+			t.Errorf("got %v, wanted %v", got, want)
+		}
+	}
+
+	// a link to a heading is not a footnote
+	if strings.Contains(got, `<a href="#intro" role="doc-noteref">`) {
+		t.Errorf("got %v, wanted intro link untouched", got)
+	}
+
+}
+
+func TestFootnotesFormats(t *testing.T) {
+
+	server := footnotesServer()
+	defer server.Close()
+
+	c := NewChapterFromURL(server.URL+"/post", "", []*ScrapeConfig{NewScrapeConfig()}, 0, func(index int, name string) {})
+
+	book := NewEmptyChapter()
+	book.AddSubChapter(c)
+	book.AddSubChapter(c)
+
+	markdown := ToMarkdownString(book)
+	for _, want := range []string{"First claim[^1]", "second claim[^2]", "[^1]: First note.\n", "[^2]: Second _note_.", "First claim[^3]", "[^4]: Second _note_."} {
+		if strings.Contains(markdown, want) == false {
+			t.Errorf("got %v, wanted %v", markdown, want)
+		}
+	}
+	if strings.Contains(markdown, "↩") {
+		t.Errorf("got %v, wanted no backlink", markdown)
+	}
+
+	epub := epubFootnotes(c.Content())
+	for _, want := range []string{`epub:type="noteref"`, `<aside epub:type="footnote" role="doc-footnote" id="fn1"><p>First note. </p></aside>`, `<aside epub:type="footnote" role="doc-footnote" id="cite_note-2">Second <em>note</em>.</aside>`} {
+		if strings.Contains(epub, want) == false {
+			t.Errorf("got %v, wanted %v", epub, want)
+		}
+	}
+	if strings.Contains(epub, "<ol>") {
+		t.Errorf("got %v, wanted empty list of notes removed", epub)
+	}
+
+}
@@ -2,6 +2,7 @@ package book
 
 import (
 	"fmt"
+	"html"
 	"log"
 	"net/url"
 	"os"
@@ -9,7 +10,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/PuerkitoBio/goquery"
 	epub "github.com/bmaupin/go-epub"
 )
@@ -24,6 +24,12 @@ func Filename(name string) string {
 }
 
 func ToMarkdownString(c chapter) string {
+	notes := 0
+	return toMarkdownString(c, &notes)
+}
+
+// toMarkdownString numbers the footnotes of the file after notes
+func toMarkdownString(c chapter, notes *int) string {
 	markdown := ""
 
 	// chapter content
@@ -32,11 +38,17 @@ func ToMarkdownString(c chapter) string {
 		markdown += fmt.Sprintf("%s\n", c.Name())
 		markdown += fmt.Sprintf("%s\n\n", strings.Repeat("=", len(c.Name())))
 
-		// convert content to markdown
-		content, err := md.NewConverter("", true, nil).ConvertString(c.Content())
-		if err != nil {
-			log.Fatal(err)
+		// author, date and source
+		if c.config.ShowMetadata {
+			if line := metadataLine(c); len(line) > 0 {
+				markdown += fmt.Sprintf("_%s_\n\n", line)
+			}
+			markdown += fmt.Sprintf("Source: <%s>\n\n", c.Url())
 		}
+
+		// convert content to markdown
+		content, n := markdownFootnotes(c.Content(), *notes+1)
+		*notes += n
 		markdown += fmt.Sprintf("%s\n\n\n", content)
 	}
 
@@ -50,7 +62,7 @@ func ToMarkdownString(c chapter) string {
 	} else {
 		// subchapters content
 		for _, sc := range c.SubChapters() {
-			markdown += fmt.Sprintf("%s\n\n\n", ToMarkdownString(sc))
+			markdown += fmt.Sprintf("%s\n\n\n", toMarkdownString(sc, notes))
 		}
 	}
 
@@ -141,7 +153,8 @@ func ToHtmlString(c chapter) string {
 
 	// chapter content
 	if c.config.Include {
-		html += fmt.Sprintf("<h1>%s</h1>", c.Name())
+		html += fmt.Sprintf("<h1%s>%s</h1>", anchorAttr(c), c.Name())
+		html += metadataHtml(c)
 		html += c.Content()
 	}
 
@@ -153,12 +166,41 @@ func ToHtmlString(c chapter) string {
 	return html
 }
 
+// anchorAttr returns the id attribute of the chapter title, empty if the chapter has no anchor
+func anchorAttr(c chapter) string {
+	if len(c.anchor) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(` id="%s"`, c.anchor)
+}
+
+// metadataHtml returns the author, date and source of the chapter if metadata are shown
+func metadataHtml(c chapter) string {
+	if c.config.ShowMetadata == false {
+		return ""
+	}
+
+	p := `<p class="metadata">`
+	if line := metadataLine(c); len(line) > 0 {
+		p += fmt.Sprintf("<em>%s</em><br/>", html.EscapeString(line))
+	}
+	p += fmt.Sprintf(`<a href="%s">%s</a></p>`, html.EscapeString(c.Url()), html.EscapeString(c.Url()))
+
+	return p
+}
+
 func ToHtml(c chapter, filename string) string {
 	if len(filename) == 0 {
 		filename = fmt.Sprintf("%s.html", Filename(c.Name()))
 	}
 
-	html := fmt.Sprintf("<html><head></head><body>%s</body></html>", ToHtmlString(c))
+	lang := ""
+	if m := bookMetadata(c); len(m.Language) > 0 {
+		lang = fmt.Sprintf(` lang="%s"`, m.Language)
+	}
+
+	html := fmt.Sprintf("<html%s><head></head><body>%s</body></html>", lang, ToHtmlString(c))
 
 	// write to file
 	f, err := os.Create(filename)
@@ -183,6 +225,14 @@ func ToEpub(c chapter, filename string) string {
 	e := epub.NewEpub(c.Name())
 	e.SetAuthor(c.Author())
 
+	m := bookMetadata(c)
+	if len(m.Language) > 0 {
+		e.SetLang(m.Language)
+	}
+	if len(m.Excerpt) > 0 {
+		e.SetDescription(m.Excerpt)
+	}
+
 	AppendToEpub(e, c)
 
 	err := e.Write(filename)
@@ -194,14 +244,20 @@ func ToEpub(c chapter, filename string) string {
 }
 
 func AppendToEpub(e *epub.Epub, c chapter) {
+	appendToEpub(e, c, "")
+}
+
+// appendToEpub adds the chapter as a subsection of parent if not empty
+func appendToEpub(e *epub.Epub, c chapter, parent string) {
 	content := ""
+	filename := ""
 
-	// append table of content
-	if len(c.SubChapters()) > 1 {
+	// append table of content, the sections of a chapter are listed in the book table of contents
+	if len(c.SubChapters()[c.sections:]) > 1 {
 		html := "<h1>Table of Contents</h1>"
 
 		html += "<ol>"
-		for _, sc := range c.SubChapters() {
+		for _, sc := range c.SubChapters()[c.sections:] {
 			html += fmt.Sprintf("<li>%s</li>", sc.Name())
 		}
 		html += "</ol>"
@@ -216,7 +272,7 @@ func AppendToEpub(e *epub.Epub, c chapter) {
 	if c.config.Include {
 
 		if c.config.ImagesOnly == false {
-			content = c.Content()
+			content = epubFootnotes(c.Content())
 		}
 
 		// parse content
@@ -243,20 +299,30 @@ func AppendToEpub(e *epub.Epub, c chapter) {
 		// add title only if ImagesOnly = false
 		if c.config.ImagesOnly == false {
 			html += fmt.Sprintf("<h1>%s</h1>", c.Name())
+			html += metadataHtml(c)
 		}
 		html += content
 
 		//  write to epub file
-		_, err = e.AddSection(html, c.Name(), "", "")
+		if len(parent) > 0 {
+			filename, err = e.AddSubSection(parent, html, c.Name(), epubFilename(c), "")
+		} else {
+			filename, err = e.AddSection(html, c.Name(), epubFilename(c), "")
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
 
 	}
 
-	// subchapters content
-	for _, sc := range c.SubChapters() {
-		AppendToEpub(e, sc)
+	// subchapters content, sections are nested under the chapter
+	for index, sc := range c.SubChapters() {
+		sectionParent := ""
+		if index < c.sections {
+			sectionParent = filename
+		}
+
+		appendToEpub(e, sc, sectionParent)
 	}
 }
 
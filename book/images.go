@@ -0,0 +1,205 @@
+package book
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// attributes holding the real source of lazy loaded images, the first one found is used
+var (
+	lazySrcAttrs    = []string{"data-src", "data-original", "data-lazy-src", "data-url", "data-orig-file", "data-hi-res-src"}
+	lazySrcsetAttrs = []string{"data-srcset", "data-lazy-srcset", "data-original-set"}
+)
+
+// image types most e-readers can display, other <picture> sources are ignored
+var supportedImageTypes = map[string]bool{
+	"":              true,
+	"image/jpeg":    true,
+	"image/jpg":     true,
+	"image/png":     true,
+	"image/gif":     true,
+	"image/svg+xml": true,
+}
+
+var backgroundImageRegexp = regexp.MustCompile(`background(?:-image)?\s*:[^;]*url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// srcsetCandidate is an image URL of a srcset along with its width or pixel density descriptor
+type srcsetCandidate struct {
+	url     string
+	width   int
+	density float64
+}
+
+// fixImages turns lazy loaded, noscript, responsive and background images of page into plain <img> elements,
+// so extractors and readers see the real image. maxWidth is the preferred width of responsive images, 0 means the largest.
+func fixImages(page *goquery.Document, maxWidth int) {
+	unwrapNoscriptImages(page)
+
+	// lazy loaded images keep a placeholder in src
+	page.Find("img, source").Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "img" {
+			for _, attr := range lazySrcAttrs {
+				if value := strings.TrimSpace(s.AttrOr(attr, "")); len(value) > 0 && strings.HasPrefix(value, "data:") == false {
+					s.SetAttr("src", value)
+					break
+				}
+			}
+		}
+
+		for _, attr := range lazySrcsetAttrs {
+			if value := strings.TrimSpace(s.AttrOr(attr, "")); len(value) > 0 {
+				s.SetAttr("srcset", value)
+				break
+			}
+		}
+	})
+
+	// responsive images
+	page.Find("img").Each(func(i int, s *goquery.Selection) {
+		candidates := parseSrcset(s.AttrOr("srcset", ""))
+
+		if picture := s.Parent(); goquery.NodeName(picture) == "picture" {
+			picture.Find("source[srcset]").Each(func(i int, source *goquery.Selection) {
+				if supportedImageTypes[strings.ToLower(strings.TrimSpace(source.AttrOr("type", "")))] {
+					candidates = append(candidates, parseSrcset(source.AttrOr("srcset", ""))...)
+				}
+			})
+		}
+
+		if best, exists := bestCandidate(candidates, maxWidth); exists {
+			s.SetAttr("src", best)
+		}
+		s.RemoveAttr("srcset")
+		s.RemoveAttr("sizes")
+	})
+
+	// images set in inline styles are not part of the content otherwise
+	page.Find("[style*=url]").Each(func(i int, s *goquery.Selection) {
+		match := backgroundImageRegexp.FindStringSubmatch(s.AttrOr("style", ""))
+		if match == nil || strings.HasPrefix(match[1], "data:") || s.Find("img").Length() > 0 {
+			return
+		}
+
+		s.PrependHtml(fmt.Sprintf(`<img src="%s"/>`, html.EscapeString(strings.TrimSpace(match[1]))))
+	})
+}
+
+// unwrapNoscriptImages replaces <noscript> elements containing images with those images,
+// the placeholder image before them is removed
+func unwrapNoscriptImages(page *goquery.Document) {
+	page.Find("noscript").Each(func(i int, s *goquery.Selection) {
+		// noscript content is parsed as text
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(s.Text()))
+		if err != nil {
+			return
+		}
+
+		images := doc.Find("body img")
+		if images.Length() == 0 {
+			return
+		}
+
+		content := ""
+		images.Each(func(i int, image *goquery.Selection) {
+			element, _ := goquery.OuterHtml(image)
+			content += element
+		})
+
+		if previous := s.Prev(); goquery.NodeName(previous) == "img" {
+			previous.Remove()
+		}
+
+		s.ReplaceWithHtml(content)
+	})
+}
+
+// parseSrcset returns the candidates of a srcset attribute, URLs may contain commas
+func parseSrcset(srcset string) []srcsetCandidate {
+	candidates := []srcsetCandidate{}
+
+	rest := srcset
+	for {
+		rest = strings.TrimLeftFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+		if len(rest) == 0 {
+			break
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end == -1 {
+			end = len(rest)
+		}
+		url := rest[:end]
+		rest = rest[end:]
+
+		descriptor := ""
+		if strings.HasSuffix(url, ",") {
+			url = strings.TrimRight(url, ",")
+		} else {
+			end = strings.Index(rest, ",")
+			if end == -1 {
+				end = len(rest)
+			}
+			descriptor = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+
+		c := srcsetCandidate{url, 0, 1}
+		if strings.HasSuffix(descriptor, "w") {
+			c.width, _ = strconv.Atoi(strings.TrimSuffix(descriptor, "w"))
+		} else if strings.HasSuffix(descriptor, "x") {
+			if density, err := strconv.ParseFloat(strings.TrimSuffix(descriptor, "x"), 64); err == nil {
+				c.density = density
+			}
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+// bestCandidate returns the largest image not wider than maxWidth, or the smallest one if they are all wider.
+// Without widths, the highest pixel density up to 2x is used.
+func bestCandidate(candidates []srcsetCandidate, maxWidth int) (string, bool) {
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	widths := []srcsetCandidate{}
+	for _, c := range candidates {
+		if c.width > 0 {
+			widths = append(widths, c)
+		}
+	}
+
+	if len(widths) > 0 {
+		sort.SliceStable(widths, func(i, j int) bool { return widths[i].width < widths[j].width })
+
+		best := widths[0]
+		for _, c := range widths {
+			if maxWidth <= 0 || c.width <= maxWidth {
+				best = c
+			}
+		}
+
+		return best.url, true
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].density < candidates[j].density })
+
+	best := candidates[0]
+	for _, c := range candidates {
+		if maxWidth <= 0 || c.density <= 2 {
+			best = c
+		}
+	}
+
+	return best.url, true
+}
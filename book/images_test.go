@@ -0,0 +1,75 @@
+package book
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseSrcset(t *testing.T) {
+
+	got := fmt.Sprint(parseSrcset(" small.jpg 480w, https://cdn.example.com/w_800,h_600/large.jpg 800w,plain.jpg, retina.jpg 2x"))
+	want := "[{small.jpg 480 1} {https://cdn.example.com/w_800,h_600/large.jpg 800 1} {plain.jpg 0 1} {retina.jpg 0 2}]"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestBestCandidate(t *testing.T) {
+
+	widths := parseSrcset("a.jpg 400w, b.jpg 1000w, c.jpg 2000w")
+	densities := parseSrcset("a.jpg, b.jpg 2x, c.jpg 3x")
+
+	for _, test := range []struct {
+		candidates []srcsetCandidate
+		maxWidth   int
+		want       string
+	}{
+		{widths, 1200, "b.jpg"},
+		{widths, 0, "c.jpg"},
+		{widths, 300, "a.jpg"},
+		{densities, 1200, "b.jpg"},
+		{densities, 0, "c.jpg"},
+	} {
+		got, _ := bestCandidate(test.candidates, test.maxWidth)
+
+		if got != test.want {
+			t.Errorf("got %v, wanted %v", got, test.want)
+		}
+	}
+
+}
+
+func TestFixImages(t *testing.T) {
+
+	page, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+		<img id="lazy" src="placeholder.gif" data-src="lazy.jpg">
+		<img src="placeholder.gif" class="lazy"><noscript><img id="noscript" src="noscript.jpg"></noscript>
+		<picture>
+			<source type="image/avif" srcset="photo.avif 1000w">
+			<source srcset="photo-600.jpg 600w, photo-1000.jpg 1000w, photo-1600.jpg 1600w">
+			<img id="picture" src="photo-300.jpg">
+		</picture>
+		<div id="background" style="background-image: url('/header.png'); height: 10px"></div>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixImages(page, 1200)
+
+	got := []string{}
+	page.Find("img").Each(func(i int, s *goquery.Selection) {
+		got = append(got, s.AttrOr("src", ""))
+	})
+	want := "[lazy.jpg noscript.jpg photo-1000.jpg /header.png]"
+
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
@@ -0,0 +1,220 @@
+package book
+
+import (
+	"encoding/json"
+	"fmt"
+	urllib "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaesslerAG/jsonpath"
+)
+
+// maximum number of pages followed with the next page expression, in case the API loops
+const jsonMaxPages = 1000
+
+// date formats tried on JSON dates, numbers are UNIX timestamps
+var jsonDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", time.RFC1123Z, time.RFC1123}
+
+// CheckJSONPath returns an error if expression is not a valid JSONPath expression
+func CheckJSONPath(expression string) error {
+	if len(expression) == 0 {
+		return nil
+	}
+
+	if _, err := jsonpath.New(expression); err != nil {
+		return fmt.Errorf("invalid JSONPath expression %s: %v", expression, err)
+	}
+
+	return nil
+}
+
+// getJSONLinks reads links from the JSON document at url, following the next page expression if any
+func getJSONLinks(url *urllib.URL, config *ScrapeConfig) ([]link, error) {
+	links := []link{}
+	visited := map[string]bool{}
+
+	pageURL := url
+	for page := 0; page < jsonMaxPages; page++ {
+		visited[pageURL.String()] = true
+
+		data, err := getJSON(pageURL.String(), config)
+		if err != nil {
+			return links, err
+		}
+
+		pageLinks, err := jsonLinks(pageURL, data, config)
+		if err != nil {
+			return links, err
+		}
+		links = append(links, pageLinks...)
+
+		// an empty page means there is nothing left
+		if len(config.JSONNext) == 0 || len(pageLinks) == 0 {
+			break
+		}
+
+		// a missing next value ends the pagination
+		value, err := jsonpath.Get(config.JSONNext, data)
+		if err != nil {
+			break
+		}
+		next := jsonString(value)
+		if len(next) == 0 {
+			break
+		}
+
+		if len(config.JSONCursorParam) > 0 {
+			// the next value is a cursor to set on the first URL
+			u := *url
+			query := u.Query()
+			query.Set(config.JSONCursorParam, next)
+			u.RawQuery = query.Encode()
+			pageURL = &u
+		} else {
+			// the next value is the URL of the next page
+			pageURL, err = pageURL.Parse(next)
+			if err != nil {
+				return links, err
+			}
+		}
+
+		if visited[pageURL.String()] {
+			break
+		}
+	}
+
+	return links, nil
+}
+
+func getJSON(url string, config *ScrapeConfig) (interface{}, error) {
+	response, err := httpClient(config).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to get %s: %s", url, response.Status)
+	}
+
+	// keep large identifiers and cursors intact
+	var data interface{}
+	decoder := json.NewDecoder(response.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON at %s: %v", url, err)
+	}
+
+	return data, nil
+}
+
+// jsonLinks extracts the links of a JSON document, titles and dates are matched with links by position
+func jsonLinks(base *urllib.URL, data interface{}, config *ScrapeConfig) ([]link, error) {
+	hrefs, err := jsonStrings(config.JSONLinks, data)
+	if err != nil {
+		return []link{}, err
+	}
+
+	titles := []string{}
+	if len(config.JSONTitles) > 0 {
+		titles, err = jsonStrings(config.JSONTitles, data)
+		if err != nil {
+			return []link{}, err
+		}
+
+		if len(titles) != len(hrefs) {
+			return []link{}, fmt.Errorf("found %d titles for %d links", len(titles), len(hrefs))
+		}
+	}
+
+	dates := []string{}
+	if len(config.JSONDates) > 0 {
+		dates, err = jsonStrings(config.JSONDates, data)
+		if err != nil {
+			return []link{}, err
+		}
+
+		if len(dates) != len(hrefs) {
+			return []link{}, fmt.Errorf("found %d dates for %d links", len(dates), len(hrefs))
+		}
+	}
+
+	links := []link{}
+	for index, href := range hrefs {
+		u, err := base.Parse(href)
+		if err != nil {
+			return []link{}, err
+		}
+
+		text := u.String()
+		if len(titles) > 0 {
+			text = titles[index]
+		}
+
+		date := &time.Time{}
+		if len(dates) > 0 {
+			date = parseJSONDate(dates[index])
+		}
+
+		links = append(links, NewLink(u.String(), text, date))
+	}
+
+	return links, nil
+}
+
+// jsonStrings returns the values matching expression, a single value gives a list of one
+func jsonStrings(expression string, data interface{}) ([]string, error) {
+	value, err := jsonpath.Get(expression, data)
+	if err != nil {
+		return []string{}, fmt.Errorf("JSONPath expression %s: %v", expression, err)
+	}
+
+	values, ok := value.([]interface{})
+	if ok == false {
+		values = []interface{}{value}
+	}
+
+	result := []string{}
+	for _, v := range values {
+		result = append(result, jsonString(v))
+	}
+
+	return result, nil
+}
+
+// jsonString returns a JSON scalar as a string, or an empty string for null and objects
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	return ""
+}
+
+// parseJSONDate parses a date string or a UNIX timestamp in seconds or milliseconds, the zero time if it fails
+func parseJSONDate(s string) *time.Time {
+	if timestamp, err := strconv.ParseInt(s, 10, 64); err == nil {
+		date := time.Unix(timestamp, 0)
+		if timestamp > 1e12 {
+			date = time.UnixMilli(timestamp)
+		}
+		return &date
+	}
+
+	for _, layout := range jsonDateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return &date
+		}
+	}
+
+	return &time.Time{}
+}
@@ -0,0 +1,96 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	urllib "net/url"
+	"testing"
+)
+
+func newJSONServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"items": [{"url": "/1", "title": "One", "date": "2022-01-01"}, {"url": "/2", "title": "Two", "date": 1641081600}], "next": "abc", "next_url": "/api?cursor=abc"}`)
+		case "abc":
+			fmt.Fprint(w, `{"items": [{"url": "/3", "title": "Three", "date": "2022-01-03T00:00:00Z"}], "next": null}`)
+		}
+	}))
+}
+
+func TestGetLinksJSON(t *testing.T) {
+
+	server := newJSONServer()
+	defer server.Close()
+
+	base, _ := urllib.Parse(server.URL + "/api")
+
+	for _, next := range []struct{ expression, param string }{{"$.next", "cursor"}, {"$.next_url", ""}} {
+		config := NewScrapeConfig()
+		config.JSONLinks = "$.items[*].url"
+		config.JSONTitles = "$.items[*].title"
+		config.JSONDates = "$.items[*].date"
+		config.JSONNext = next.expression
+		config.JSONCursorParam = next.param
+
+		// the JSON document itself is never a chapter
+		links, path, home, err := GetLinks(base, config, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		for _, l := range links {
+			got = append(got, fmt.Sprintf("%s %s %s", l.Href[len(server.URL):], l.Text, l.Date.UTC().Format("2006-01-02")))
+		}
+		want := []string{"/1 One 2022-01-01", "/2 Two 2022-01-02", "/3 Three 2022-01-03"}
+
+		if fmt.Sprint(got) != fmt.Sprint(want) || path != "JSON" {
+			t.Errorf("%s: got %v %v, wanted %v JSON", next.expression, got, path, want)
+		}
+		if home.Name() != base.Host {
+			t.Errorf("got %v, wanted %v", home.Name(), base.Host)
+		}
+	}
+
+}
+
+func TestNewChapterFromJSON(t *testing.T) {
+
+	server := newJSONServer()
+	defer server.Close()
+
+	config := NewScrapeConfig()
+	config.JSONLinks = "$.items[*].url"
+	config.JSONTitles = "$.items[*].title"
+
+	subConfig := NewScrapeConfig()
+	subConfig.UseLinkName = true
+
+	// the API is not parsed as a page, the book is named after its host
+	c := NewChapterFromURL(server.URL+"/api", "", []*ScrapeConfig{config, subConfig}, 0, func(index int, name string) {})
+
+	base, _ := urllib.Parse(server.URL)
+	got := fmt.Sprint(c.Name(), len(c.SubChapters()), c.SubChapters()[0].Name())
+	want := fmt.Sprint(base.Host, 2, "One")
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestCheckJSONPath(t *testing.T) {
+
+	if err := CheckJSONPath("$.items[*].url"); err != nil {
+		t.Errorf("got %v, wanted no error", err)
+	}
+
+	if err := CheckJSONPath("$.items[*"); err == nil {
+		t.Errorf("got no error, wanted an error")
+	}
+
+}
@@ -1,6 +1,10 @@
 package book
 
-import "time"
+import (
+	urllib "net/url"
+	"strings"
+	"time"
+)
 
 type link struct {
 	Href string     `json:"url"`
@@ -11,3 +15,99 @@ type link struct {
 func NewLink(href, text string, date *time.Time) link {
 	return link{href, text, date}
 }
+
+// query parameters added by newsletters and ad platforms that never change the page content
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"ref_src": true,
+}
+
+// CleanURL returns a copy of u without fragment and tracking parameters,
+// with a lowercase scheme and host and without default port.
+// The result is still suitable for downloading the page.
+func CleanURL(u *urllib.URL) *urllib.URL {
+	clean := *u
+	clean.Fragment = ""
+	clean.RawFragment = ""
+	clean.Scheme = strings.ToLower(clean.Scheme)
+	clean.Host = strings.ToLower(clean.Host)
+
+	// remove default port
+	if (clean.Scheme == "http" && strings.HasSuffix(clean.Host, ":80")) || (clean.Scheme == "https" && strings.HasSuffix(clean.Host, ":443")) {
+		clean.Host = clean.Host[:strings.LastIndex(clean.Host, ":")]
+	}
+
+	// remove tracking parameters, keep the query untouched otherwise
+	if len(clean.RawQuery) > 0 {
+		query := clean.Query()
+		removed := false
+		for key := range query {
+			if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+				query.Del(key)
+				removed = true
+			}
+		}
+		if removed {
+			clean.RawQuery = query.Encode()
+		}
+	}
+
+	return &clean
+}
+
+// NormalizeURL returns the key used to compare two URLs.
+// On top of CleanURL, the query is sorted and the trailing slash of non-root paths is trimmed,
+// so that /guide/ and /guide are considered the same page.
+func NormalizeURL(u *urllib.URL) string {
+	clean := CleanURL(u)
+
+	if len(clean.RawQuery) > 0 {
+		clean.RawQuery = clean.Query().Encode()
+	}
+
+	if len(clean.Path) > 1 {
+		clean.Path = strings.TrimSuffix(clean.Path, "/")
+		clean.RawPath = ""
+	}
+	if clean.Path == "" {
+		clean.Path = "/"
+	}
+
+	return clean.String()
+}
+
+// NormalizeHref is NormalizeURL for a string URL, it returns href unchanged if it cannot be parsed.
+func NormalizeHref(href string) string {
+	u, err := urllib.Parse(href)
+	if err != nil {
+		return href
+	}
+
+	return NormalizeURL(u)
+}
+
+// dedupeLinks removes links pointing to the same normalized URL, keeping the first occurrence.
+func dedupeLinks(links []link) []link {
+	seen := map[string]bool{}
+	unique := []link{}
+
+	for _, l := range links {
+		key := NormalizeHref(l.Href)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		unique = append(unique, l)
+	}
+
+	return unique
+}
@@ -0,0 +1,60 @@
+package book
+
+import (
+	urllib "net/url"
+	"testing"
+	"time"
+)
+
+func TestNormalizeURL(t *testing.T) {
+
+	tests := map[string]string{
+		"https://Example.com:443/guide/#install":           "https://example.com/guide",
+		"https://example.com/guide?utm_source=rss&b=2&a=1": "https://example.com/guide?a=1&b=2",
+		"http://example.com:80":                            "http://example.com/",
+		"https://example.com/?fbclid=abc":                  "https://example.com/",
+	}
+
+	for input, want := range tests {
+		u, _ := urllib.Parse(input)
+		got := NormalizeURL(u)
+
+		if got != want {
+			t.Errorf("got %v, wanted %v", got, want)
+		}
+	}
+
+}
+
+func TestCleanURLKeepsTrailingSlash(t *testing.T) {
+
+	u, _ := urllib.Parse("https://example.com/guide/?utm_medium=email#top")
+
+	got := CleanURL(u).String()
+	want := "https://example.com/guide/"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestDedupeLinks(t *testing.T) {
+
+	links := []link{
+		NewLink("https://example.com/chapter-1", "Chapter 1", &time.Time{}),
+		NewLink("https://example.com/chapter-1/#comments", "Read more", &time.Time{}),
+		NewLink("https://example.com/chapter-2?utm_campaign=toc", "Chapter 2", &time.Time{}),
+		NewLink("https://example.com/chapter-2", "Chapter 2", &time.Time{}),
+	}
+
+	got := dedupeLinks(links)
+
+	if len(got) != 2 {
+		t.Fatalf("got %v links, wanted %v", len(got), 2)
+	}
+	if got[0].Text != "Chapter 1" {
+		t.Errorf("got %v, wanted %v", got[0].Text, "Chapter 1")
+	}
+
+}
@@ -0,0 +1,214 @@
+package book
+
+import (
+	"encoding/json"
+	urllib "net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	readability "github.com/go-shiori/go-readability"
+)
+
+// Metadata describes the page a chapter comes from, empty fields are unknown
+type Metadata struct {
+	Published *time.Time `json:"published,omitempty"`
+	Modified  *time.Time `json:"modified,omitempty"`
+	Language  string     `json:"language,omitempty"`
+	SiteName  string     `json:"siteName,omitempty"`
+	Image     string     `json:"image,omitempty"`
+	Excerpt   string     `json:"excerpt,omitempty"`
+	Canonical string     `json:"canonical,omitempty"`
+	Fetched   *time.Time `json:"fetched,omitempty"`
+}
+
+// meta tags holding the dates and language, the first one found is used
+var (
+	publishedSelectors = []string{`meta[property="article:published_time"]`, `meta[name="article:published_time"]`, `meta[itemprop="datePublished"]`, `meta[name="date"]`, `meta[name="dc.date"]`, `meta[name="DC.date.issued"]`, `meta[property="og:published_time"]`}
+	modifiedSelectors  = []string{`meta[property="article:modified_time"]`, `meta[name="article:modified_time"]`, `meta[itemprop="dateModified"]`, `meta[property="og:updated_time"]`, `meta[name="last-modified"]`}
+	languageSelectors  = []string{`meta[http-equiv="content-language"]`, `meta[name="language"]`, `meta[property="og:locale"]`}
+)
+
+// pageMetadata gathers the metadata of page from its meta tags, JSON-LD data and readability result
+func pageMetadata(base *urllib.URL, page *goquery.Document, article readability.Article, canonical string) Metadata {
+	fetched := time.Now()
+	m := Metadata{nil, nil, "", article.SiteName, "", strings.TrimSpace(article.Excerpt), canonical, &fetched}
+
+	ld := jsonLDMetadata(page)
+
+	m.Published = metaDate(page, publishedSelectors)
+	if m.Published == nil {
+		m.Published = parseMetadataDate(ld["datePublished"])
+	}
+
+	m.Modified = metaDate(page, modifiedSelectors)
+	if m.Modified == nil {
+		m.Modified = parseMetadataDate(ld["dateModified"])
+	}
+
+	m.Language = strings.TrimSpace(page.Find("html").AttrOr("lang", ""))
+	if len(m.Language) == 0 {
+		m.Language = metaContent(page, languageSelectors)
+	}
+	if len(m.Language) == 0 {
+		m.Language = ld["inLanguage"]
+	}
+	// og:locale uses underscores
+	m.Language = strings.ReplaceAll(m.Language, "_", "-")
+
+	if len(m.SiteName) == 0 {
+		m.SiteName = metaContent(page, []string{`meta[property="og:site_name"]`, `meta[name="application-name"]`})
+	}
+
+	image := article.Image
+	if len(image) == 0 {
+		image = ld["image"]
+	}
+	if u, err := base.Parse(strings.TrimSpace(image)); err == nil && len(image) > 0 {
+		m.Image = u.String()
+	}
+
+	return m
+}
+
+// metaContent returns the content of the first meta tag found
+func metaContent(page *goquery.Document, selectors []string) string {
+	for _, selector := range selectors {
+		if content := strings.TrimSpace(page.Find(selector).First().AttrOr("content", "")); len(content) > 0 {
+			return content
+		}
+	}
+
+	return ""
+}
+
+// metaDate returns the date of the first meta tag found, or nil
+func metaDate(page *goquery.Document, selectors []string) *time.Time {
+	return parseMetadataDate(metaContent(page, selectors))
+}
+
+// parseMetadataDate returns the date of s, or nil if it is not a known date format
+func parseMetadataDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return nil
+	}
+
+	date := parseJSONDate(s)
+	if date.IsZero() {
+		return nil
+	}
+
+	return date
+}
+
+// jsonLDMetadata returns the string fields of the first JSON-LD object with a date or a language
+func jsonLDMetadata(page *goquery.Document) map[string]string {
+	fields := map[string]string{}
+
+	page.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var data interface{}
+		if json.Unmarshal([]byte(s.Text()), &data) != nil {
+			return true
+		}
+
+		fields = jsonLDFields(data)
+		return len(fields) == 0
+	})
+
+	return fields
+}
+
+func jsonLDFields(data interface{}) map[string]string {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if fields := jsonLDFields(item); len(fields) > 0 {
+				return fields
+			}
+		}
+
+	case map[string]interface{}:
+		fields := map[string]string{}
+		for _, key := range []string{"datePublished", "dateModified", "inLanguage"} {
+			if value, ok := v[key].(string); ok {
+				fields[key] = value
+			}
+		}
+		if len(fields) > 0 {
+			fields["image"] = jsonLDImage(v["image"])
+			return fields
+		}
+
+		if graph, exists := v["@graph"]; exists {
+			return jsonLDFields(graph)
+		}
+	}
+
+	return map[string]string{}
+}
+
+// jsonLDImage returns the URL of a schema.org image, which may be a string, an ImageObject or a list of them
+func jsonLDImage(data interface{}) string {
+	switch v := data.(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			return jsonLDImage(v[0])
+		}
+	case map[string]interface{}:
+		url, _ := v["url"].(string)
+		return url
+	}
+
+	return ""
+}
+
+// metadataLine returns the author, site name and publication date of the chapter, separated by dots
+func metadataLine(c chapter) string {
+	fields := []string{}
+	for _, field := range []string{c.Author(), c.metadata.SiteName} {
+		if field = strings.TrimSpace(field); len(field) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	if c.metadata.Published != nil {
+		fields = append(fields, c.metadata.Published.Format("2006-01-02"))
+	}
+
+	return strings.Join(fields, " · ")
+}
+
+// bookMetadata returns the metadata of the first chapter of the book coming from a web page
+func bookMetadata(c chapter) Metadata {
+	if len(c.url) > 0 {
+		return c.metadata
+	}
+
+	for _, sc := range c.SubChapters() {
+		if m := bookMetadata(sc); m.Fetched != nil {
+			return m
+		}
+	}
+
+	return Metadata{}
+}
+
+// ChapterInfo is the name, URL and metadata of a chapter and its subchapters, used by JSON outputs
+type ChapterInfo struct {
+	Name     string        `json:"name"`
+	Url      string        `json:"url"`
+	Author   string        `json:"author,omitempty"`
+	Metadata Metadata      `json:"metadata"`
+	Chapters []ChapterInfo `json:"chapters,omitempty"`
+}
+
+func NewChapterInfo(c chapter) ChapterInfo {
+	chapters := []ChapterInfo{}
+	for _, sc := range c.SubChapters() {
+		chapters = append(chapters, NewChapterInfo(sc))
+	}
+
+	return ChapterInfo{c.Name(), c.Url(), c.Author(), c.Metadata(), chapters}
+}
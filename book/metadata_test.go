@@ -0,0 +1,72 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPageMetadata(t *testing.T) {
+
+	paragraph := strings.Repeat("This sentence is part of the article. ", 20)
+
+	pages := map[string]string{
+		"/meta": `<html lang="fr"><head><title>Post</title>
+			<meta property="og:site_name" content="Blog">
+			<meta property="og:image" content="/cover.png">
+			<meta property="article:published_time" content="2023-04-05T10:00:00Z">
+			<meta property="article:modified_time" content="2023-04-06T10:00:00Z">`,
+		"/ld": `<html><head><title>Post</title>
+			<meta property="og:locale" content="en_GB">
+			<script type="application/ld+json">{"@graph": [{"@type": "WebSite"}, {"@type": "Article", "datePublished": "2022-01-02", "image": {"url": "https://example.com/ld.png"}}]}</script>`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `%s</head><body><article><p>%s</p></article></body></html>`, pages[r.URL.Path], paragraph)
+	}))
+	defer server.Close()
+
+	for path, want := range map[string]string{
+		"/meta": fmt.Sprintf("2023-04-05 2023-04-06 fr Blog %s/cover.png", server.URL),
+		"/ld":   "2022-01-02 ? en-GB  https://example.com/ld.png",
+	} {
+		c := NewChapterFromURL(server.URL+path, "", []*ScrapeConfig{NewScrapeConfig()}, 0, func(index int, name string) {})
+		m := c.Metadata()
+
+		modified := "?"
+		if m.Modified != nil {
+			modified = m.Modified.Format("2006-01-02")
+		}
+
+		got := fmt.Sprintf("%s %s %s %s %s", m.Published.Format("2006-01-02"), modified, m.Language, m.SiteName, m.Image)
+
+		if got != want {
+			t.Errorf("%s: got %v, wanted %v", path, got, want)
+		}
+
+		if m.Canonical != server.URL+path || m.Fetched == nil || len(m.Excerpt) == 0 {
+			t.Errorf("%s: got %v, wanted canonical URL, fetch time and excerpt", path, m)
+		}
+	}
+
+}
+
+func TestShowMetadata(t *testing.T) {
+
+	config := NewScrapeConfig()
+	config.ShowMetadata = true
+
+	c := NewChapter("https://example.com/post", "", "Post", "Jane", "<p>Text</p>", []chapter{}, config)
+	c.metadata.SiteName = "Blog"
+
+	got := ToMarkdownString(c)
+	want := "Post\n====\n\n_Jane · Blog_\n\nSource: <https://example.com/post>\n\nText\n\n\n"
+
+	if got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+}
@@ -0,0 +1,341 @@
+package book
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// built-in recipes, user recipes with the same name take precedence
+//
+//go:embed recipes/*.yaml
+var builtinRecipes embed.FS
+
+// file extensions of recipes, JSON being valid YAML both are read the same way
+var recipeExtensions = []string{".yaml", ".yml", ".json"}
+
+// Recipe describes how to scrape a website, one level per depth
+type Recipe struct {
+	Name     string         `yaml:"name"`
+	Match    []string       `yaml:"match"` // regular expressions of the URLs the recipe applies to
+	Metadata RecipeMetadata `yaml:"metadata"`
+	Levels   []RecipeLevel  `yaml:"levels"`
+	Script   string         `yaml:"script"` // Starlark hooks, relative to the recipe file
+
+	patterns []*regexp.Regexp
+}
+
+type RecipeMetadata struct {
+	Title  string `yaml:"title"`
+	Author string `yaml:"author"`
+}
+
+// RecipeLevel holds the options of one level, unset options keep the command line defaults
+type RecipeLevel struct {
+	Selector        string     `yaml:"selector"`
+	Limit           *int       `yaml:"limit"`
+	Offset          int        `yaml:"offset"`
+	Chapters        string     `yaml:"chapters"`
+	Sort            string     `yaml:"sort"`
+	Reverse         bool       `yaml:"reverse"`
+	Delay           *int       `yaml:"delay"`
+	Threads         *int       `yaml:"threads"`
+	Include         *bool      `yaml:"include"`
+	UseLinkName     bool       `yaml:"use_link_name"`
+	ImagesOnly      bool       `yaml:"images_only"`
+	CrossReference  bool       `yaml:"cross_ref"`
+	Candidate       int        `yaml:"candidate"`
+	HrefAttr        string     `yaml:"href_attr"`
+	TitleAttr       string     `yaml:"title_attr"`
+	TitleSelector   string     `yaml:"title_selector"`
+	Extractor       string     `yaml:"extractor"`
+	ContentSelector stringList `yaml:"content_selector"`
+	Remove          []string   `yaml:"remove"`
+	RemoveAfter     []string   `yaml:"remove_after"`
+	JSONLinks       string     `yaml:"json_links"`
+	JSONTitles      string     `yaml:"json_titles"`
+	JSONDates       string     `yaml:"json_dates"`
+	JSONNext        string     `yaml:"json_next"`
+	JSONCursorParam string     `yaml:"json_cursor_param"`
+}
+
+// stringList is a list of strings which can be written as a single string
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = []string{value.Value}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+
+	return nil
+}
+
+// extractor returns the extractor of the level, content selectors imply the selector extractor, nil means the default one
+func (level RecipeLevel) extractor() (Extractor, error) {
+	name := level.Extractor
+	if len(name) == 0 {
+		if len(level.ContentSelector) == 0 {
+			return nil, nil
+		}
+		name = "selector"
+	}
+
+	return NewExtractor(name, level.ContentSelector)
+}
+
+// ParseRecipe reads a recipe in YAML or JSON format
+func ParseRecipe(data []byte) (*Recipe, error) {
+	r := &Recipe{}
+
+	// reject unknown options, most likely typos
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(r); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(r.Levels) == 0 {
+		return nil, errors.New("recipe has no level")
+	}
+
+	for _, pattern := range r.Match {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match pattern %s: %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	for depth, level := range r.Levels {
+		if _, err := ParseChapters(level.Chapters); err != nil {
+			return nil, fmt.Errorf("level %d: %v", depth, err)
+		}
+
+		if err := CheckSort(level.Sort); err != nil {
+			return nil, fmt.Errorf("level %d: %v", depth, err)
+		}
+
+		for _, expression := range []string{level.JSONLinks, level.JSONTitles, level.JSONDates, level.JSONNext} {
+			if err := CheckJSONPath(expression); err != nil {
+				return nil, fmt.Errorf("level %d: %v", depth, err)
+			}
+		}
+
+		if _, err := level.extractor(); err != nil {
+			return nil, fmt.Errorf("level %d: %v", depth, err)
+		}
+
+		if level.Delay != nil && *level.Delay >= 0 && level.Threads != nil && *level.Threads != -1 {
+			return nil, fmt.Errorf("level %d: cannot use delay and threads at the same time", depth)
+		}
+
+		if level.Candidate < 0 {
+			return nil, fmt.Errorf("level %d: candidate must be greater than 0", depth)
+		}
+	}
+
+	return r, nil
+}
+
+// LoadRecipe reads the recipe stored in filename
+func LoadRecipe(filename string) (*Recipe, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := ParseRecipe(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	if len(r.Name) == 0 {
+		r.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+
+	if len(r.Script) > 0 && filepath.IsAbs(r.Script) == false {
+		r.Script = filepath.Join(filepath.Dir(filename), r.Script)
+	}
+
+	return r, nil
+}
+
+// RecipeDirs returns the directories searched for user recipes, from $PAPEER_RECIPES then the user config directory
+func RecipeDirs() []string {
+	dirs := []string{}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PAPEER_RECIPES")) {
+		if len(dir) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	if config, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(config, "papeer", "recipes"))
+	}
+
+	return dirs
+}
+
+// FindRecipe loads a recipe from a file path, or by name from the user directories then the built-in recipes
+func FindRecipe(name string) (*Recipe, error) {
+	if info, err := os.Stat(name); err == nil && info.IsDir() == false {
+		return LoadRecipe(name)
+	}
+
+	for _, dir := range RecipeDirs() {
+		for _, ext := range recipeExtensions {
+			filename := filepath.Join(dir, name+ext)
+			if _, err := os.Stat(filename); err == nil {
+				return LoadRecipe(filename)
+			}
+		}
+	}
+
+	return builtinRecipe(name)
+}
+
+// builtinRecipe returns the recipe embedded in the binary under name
+func builtinRecipe(name string) (*Recipe, error) {
+	data, err := builtinRecipes.ReadFile("recipes/" + name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("recipe not found: %s", name)
+	}
+
+	return ParseRecipe(data)
+}
+
+// builtinConfigs returns the configs of the built-in recipe name
+func builtinConfigs(name string) []*ScrapeConfig {
+	r, err := builtinRecipe(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return r.Configs()
+}
+
+// MatchRecipe returns the first user recipe matching url, or nil if there is none
+// built-in recipes are only used by name so they never change the default behavior,
+// invalid recipe files are reported and skipped
+func MatchRecipe(url string) *Recipe {
+	for _, dir := range RecipeDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || isRecipeFile(entry.Name()) == false {
+				continue
+			}
+
+			r, err := LoadRecipe(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "skipping invalid recipe %v\n", err)
+				continue
+			}
+
+			if r.Matches(url) {
+				return r
+			}
+		}
+	}
+
+	return nil
+}
+
+func isRecipeFile(filename string) bool {
+	for _, ext := range recipeExtensions {
+		if filepath.Ext(filename) == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches tells if the recipe applies to url
+func (r *Recipe) Matches(url string) bool {
+	for _, re := range r.patterns {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Configs generates the config of each level, intermediary levels are not included unless specified
+func (r *Recipe) Configs() []*ScrapeConfig {
+	configs := make([]*ScrapeConfig, len(r.Levels))
+
+	for index, level := range r.Levels {
+		config := NewScrapeConfig()
+		config.Depth = index
+		config.Selector = level.Selector
+		config.Offset = level.Offset
+		config.Chapters = level.Chapters
+		config.Sort = level.Sort
+		config.Reverse = level.Reverse
+		config.UseLinkName = level.UseLinkName
+		config.ImagesOnly = level.ImagesOnly
+		config.CrossReference = level.CrossReference
+		config.TitleAttr = level.TitleAttr
+		config.TitleSelector = level.TitleSelector
+		config.Extractor, _ = level.extractor() // already validated
+		config.Remove = level.Remove
+		config.RemoveAfter = level.RemoveAfter
+		config.JSONLinks = level.JSONLinks
+		config.JSONTitles = level.JSONTitles
+		config.JSONDates = level.JSONDates
+		config.JSONNext = level.JSONNext
+		config.JSONCursorParam = level.JSONCursorParam
+
+		if level.Limit != nil {
+			config.Limit = *level.Limit
+		}
+
+		if level.Delay != nil {
+			config.Delay = *level.Delay
+		}
+
+		if level.Threads != nil {
+			config.Threads = *level.Threads
+		}
+
+		if level.Candidate > 0 {
+			config.Candidate = level.Candidate - 1
+		}
+
+		if len(level.HrefAttr) > 0 {
+			config.HrefAttr = level.HrefAttr
+		}
+
+		// include last level only by default
+		config.Include = index == len(r.Levels)-1
+		if level.Include != nil {
+			config.Include = *level.Include
+		}
+
+		configs[index] = config
+	}
+
+	return configs
+}
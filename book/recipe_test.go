@@ -0,0 +1,163 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRecipe(t *testing.T) {
+
+	r, err := ParseRecipe([]byte(`
+name: example
+levels:
+  - selector: .toc a
+    limit: 3
+    delay: 500
+  - content_selector: article
+    remove: [.ads]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configs := r.Configs()
+
+	got := fmt.Sprintf("%d %s %d %d %v %s %v %v", len(configs), configs[0].Selector, configs[0].Limit, configs[0].Delay, configs[0].Include, configs[1].Extractor, configs[1].Remove, configs[1].Include)
+	want := "2 .toc a 3 500 false {[article]} [.ads] true"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestParseRecipeJSON(t *testing.T) {
+
+	r, err := ParseRecipe([]byte(`{"name": "example", "levels": [{"selector": "a", "include": true}, {}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.Configs()[0].Include
+	want := true
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestParseRecipeInvalid(t *testing.T) {
+
+	for _, data := range []string{
+		``,
+		`levels: [{selectr: a}]`,
+		`levels: [{chapters: 0}]`,
+		`levels: [{extractor: selector}]`,
+		`match: ["("]
+levels: [{}]`,
+	} {
+		if _, err := ParseRecipe([]byte(data)); err == nil {
+			t.Errorf("got no error, wanted an error for %q", data)
+		}
+	}
+
+}
+
+func TestFindRecipe(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("PAPEER_RECIPES", dir)
+
+	err := os.WriteFile(filepath.Join(dir, "docs.yml"), []byte("match: ['^https://docs\\.example\\.com/']\nlevels: [{selector: nav a}, {}]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an invalid recipe does not prevent the others from matching
+	err = os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("levels: [{selectr: a}]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := FindRecipe("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.Name
+	want := "docs"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	r = MatchRecipe("https://docs.example.com/guide/")
+	if r == nil || r.Levels[0].Selector != "nav a" {
+		t.Errorf("got %v, wanted docs recipe", r)
+	}
+
+	r = MatchRecipe("https://example.com/")
+	if r != nil {
+		t.Errorf("got %v, wanted no recipe", r.Name)
+	}
+
+	// built-in recipes
+	r, err = FindRecipe("ajin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = fmt.Sprint(len(r.Configs()), r.Configs()[1].Offset, r.Configs()[2].ImagesOnly)
+	want = "3 1 true"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	configs := NewScrapeConfigsWikipedia()
+	got = fmt.Sprint(len(configs), configs[0].Include, configs[1].Include)
+	want = "2 true true"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestContentSelectorRemove(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Page</title></head><body>
+			<div class="post"><p>Kept <a href="/next">next</a></p><div class="ads">Buy</div><p class="aside">Aside</p></div>
+			<div class="comments"><p>Comment</p></div>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	config := NewScrapeConfig()
+	config.Extractor = SelectorExtractor{[]string{".post"}}
+	config.Remove = []string{".ads"}
+	config.RemoveAfter = []string{".aside"}
+
+	c := NewChapterFromURL(server.URL, "", []*ScrapeConfig{config}, 0, func(index int, name string) {})
+
+	got := c.Content()
+	for _, want := range []string{"Kept", server.URL + "/next"} {
+		if strings.Contains(got, want) == false {
+			t.Errorf("got %v, wanted %v", got, want)
+		}
+	}
+	for _, unwanted := range []string{"Buy", "Aside", "Comment"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("got %v, did not want %v", got, unwanted)
+		}
+	}
+
+}
@@ -0,0 +1,204 @@
+package book
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Renderer gets the HTML of pages from an external command, such as a headless browser, instead of a plain HTTP request
+type Renderer struct {
+	command   []string
+	timeout   time.Duration
+	semaphore chan bool
+	cacheDir  string
+
+	mu    sync.Mutex
+	cache map[string][]byte
+}
+
+// NewRenderer returns a renderer running command, where {url} is replaced by the page URL or the URL is added as last argument.
+// At most threads commands run at the same time, each one is killed after timeout.
+// Rendered pages are kept in memory, and in cacheDir if not empty.
+func NewRenderer(command string, timeout time.Duration, threads int, cacheDir string) (*Renderer, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("empty render command")
+	}
+
+	if threads < 1 {
+		return nil, errors.New("render threads must be greater than 0")
+	}
+
+	if len(cacheDir) > 0 {
+		if err := os.MkdirAll(cacheDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Renderer{args, timeout, make(chan bool, threads), cacheDir, sync.Mutex{}, map[string][]byte{}}, nil
+}
+
+// Render returns the HTML of the page at url
+func (r *Renderer) Render(url string) ([]byte, error) {
+	if body, exists := r.cached(url); exists {
+		return body, nil
+	}
+
+	args := []string{}
+	replaced := false
+	for _, arg := range r.command {
+		if strings.Contains(arg, "{url}") {
+			arg = strings.ReplaceAll(arg, "{url}", url)
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if replaced == false {
+		args = append(args, url)
+	}
+
+	r.semaphore <- true
+	defer func() { <-r.semaphore }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("render %s: timed out after %s", url, r.timeout)
+		}
+		return nil, fmt.Errorf("render %s: %v %s", url, err, strings.TrimSpace(stderr.String()))
+	}
+
+	body := stdout.Bytes()
+	r.store(url, body)
+
+	return body, nil
+}
+
+// RoundTrip renders GET requests so the renderer can be used as the transport of an HTTP client
+func (r *Renderer) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodGet {
+		return http.DefaultTransport.RoundTrip(request)
+	}
+
+	body, err := r.Render(request.URL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "text/html; charset=utf-8")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+func (r *Renderer) cacheFile(url string) string {
+	return filepath.Join(r.cacheDir, fmt.Sprintf("%x.html", sha256.Sum256([]byte(url))))
+}
+
+func (r *Renderer) cached(url string) ([]byte, bool) {
+	r.mu.Lock()
+	body, exists := r.cache[url]
+	r.mu.Unlock()
+
+	if exists || len(r.cacheDir) == 0 {
+		return body, exists
+	}
+
+	body, err := os.ReadFile(r.cacheFile(url))
+	if err != nil {
+		return nil, false
+	}
+
+	return body, true
+}
+
+func (r *Renderer) store(url string, body []byte) {
+	r.mu.Lock()
+	r.cache[url] = body
+	r.mu.Unlock()
+
+	if len(r.cacheDir) > 0 {
+		// the cache only saves time, the page is rendered again next time if it fails
+		os.WriteFile(r.cacheFile(url), body, 0644)
+	}
+}
+
+// httpClient returns the client used to download pages, going through the renderer if any
+func httpClient(config *ScrapeConfig) *http.Client {
+	if config.Renderer == nil {
+		return http.DefaultClient
+	}
+
+	return &http.Client{Transport: config.Renderer}
+}
+
+// splitCommand splits a command line into arguments, handling single and double quotes
+func splitCommand(command string) ([]string, error) {
+	args := []string{}
+	current := strings.Builder{}
+	inArg := false
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return args, fmt.Errorf("unterminated quote in command: %s", command)
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,84 @@
+package book
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommand(t *testing.T) {
+
+	args, err := splitCommand(`chromium --headless  --user-agent="My Agent" '--dump-dom' {url}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := fmt.Sprintf("%q", args)
+	want := `["chromium" "--headless" "--user-agent=My Agent" "--dump-dom" "{url}"]`
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	if _, err := splitCommand(`echo "unterminated`); err == nil {
+		t.Errorf("got no error, wanted an error")
+	}
+
+}
+
+func TestRenderer(t *testing.T) {
+
+	cache := t.TempDir()
+	paragraph := strings.Repeat("Rendered by the command. ", 30)
+
+	r, err := NewRenderer(fmt.Sprintf(`sh -c 'echo "<html><head><title>Rendered</title></head><body><article><p>%s</p><p>{url}</p></article></body></html>"'`, paragraph), time.Second, 1, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewScrapeConfig()
+	config.Renderer = r
+
+	// the host does not exist, the page only comes from the command
+	c := NewChapterFromURL("http://papeer.invalid/page", "", []*ScrapeConfig{config}, 0, func(index int, name string) {})
+
+	got := c.Name()
+	want := "Rendered"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	if strings.Contains(c.Content(), "http://papeer.invalid/page") == false {
+		t.Errorf("got %v, wanted the URL in the content", c.Content())
+	}
+
+	// a failing command is not run again for a cached page
+	r, err = NewRenderer("false", time.Second, 1, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Render("http://papeer.invalid/page"); err != nil {
+		t.Errorf("got %v, wanted cached page", err)
+	}
+
+	if _, err := r.Render("http://papeer.invalid/other"); err == nil {
+		t.Errorf("got no error, wanted command error")
+	}
+
+}
+
+func TestRendererTimeout(t *testing.T) {
+
+	r, err := NewRenderer("sh -c 'exec sleep 5'", 100*time.Millisecond, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Render("http://papeer.invalid/"); err == nil {
+		t.Errorf("got no error, wanted timeout")
+	}
+
+}
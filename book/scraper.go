@@ -6,39 +6,75 @@ import (
 	"io"
 	"log"
 	"math"
-	"net/http"
 	urllib "net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/xpath"
 	readability "github.com/go-shiori/go-readability"
 	colly "github.com/gocolly/colly/v2"
 	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
 )
 
 type ScrapeConfig struct {
-	Depth       int
-	Selector    string
-	Quiet       bool
-	Limit       int
-	Offset      int
-	Reverse     bool
-	Delay       int
-	Threads     int
-	Include     bool
-	ImagesOnly  bool
-	UseLinkName bool
+	Depth            int
+	Selector         string
+	Quiet            bool
+	Limit            int
+	Offset           int
+	Reverse          bool
+	Delay            int
+	Threads          int
+	Include          bool
+	ImagesOnly       bool
+	UseLinkName      bool
 	SeparateMarkdown bool
+	ShowMetadata     bool
+	ImageWidth       int
+	CrossReference   bool
+	Candidate        int
+	HrefAttr         string
+	TitleAttr        string
+	TitleSelector    string
+	Chapters         string
+	Extractor        Extractor
+	Remove           []string
+	RemoveAfter      []string
+	Script           *Script
+	Sort             string
+	JSONLinks        string
+	JSONTitles       string
+	JSONDates        string
+	JSONNext         string
+	JSONCursorParam  string
+	Renderer         *Renderer
 }
 
 func NewScrapeConfig() *ScrapeConfig {
-	return &ScrapeConfig{0, "", false, -1, 0, false, -1, -1, true, false, false, false}
+	return &ScrapeConfig{
+		Depth:    0,
+		Selector: "",
+		Limit:    -1,
+		Offset:   0,
+		Delay:    -1,
+		Threads:  -1,
+		Include:  true,
+		HrefAttr: "href",
+
+		// best responsive image for e-ink readers
+		ImageWidth: 1200,
+	}
 }
 
 func NewScrapeConfigNoInclude() *ScrapeConfig {
-	return &ScrapeConfig{0, "", false, -1, 0, false, -1, -1, false, false, false, false}
+	config := NewScrapeConfig()
+	config.Include = false
+
+	return config
 }
 
 func NewScrapeConfigs(selectors []string) []*ScrapeConfig {
@@ -54,41 +90,14 @@ func NewScrapeConfigs(selectors []string) []*ScrapeConfig {
 	return configs
 }
 
+// NewScrapeConfigsAjin returns the configs of the built-in ajin recipe
 func NewScrapeConfigsAjin() []*ScrapeConfig {
-	config0 := NewScrapeConfig()
-	config0.Depth = 0
-	config0.Selector = ".dt>a"
-	config0.Limit = 3
-	config0.Offset = 0
-	config0.Delay = 5000
-	config0.Include = false
-
-	config1 := NewScrapeConfig()
-	config1.Depth = 1
-	config1.Selector = ".nav_apb>a"
-	config1.Limit = 3
-	config1.Offset = 1
-	config1.Delay = 5000
-	config1.Include = false
-
-	config2 := NewScrapeConfig()
-	config2.Depth = 2
-	config2.ImagesOnly = true
-
-	return []*ScrapeConfig{config0, config1, config2}
+	return builtinConfigs("ajin")
 }
 
+// NewScrapeConfigsWikipedia returns the configs of the built-in wikipedia recipe
 func NewScrapeConfigsWikipedia() []*ScrapeConfig {
-	config0 := NewScrapeConfig()
-	config0.Depth = 0
-	config0.Threads = -1
-	config0.Include = true
-
-	config1 := NewScrapeConfig()
-	config1.Depth = 1
-	config1.Include = true
-
-	return []*ScrapeConfig{config0, config1}
+	return builtinConfigs("wikipedia")
 }
 
 func NewScrapeConfigFake() *ScrapeConfig {
@@ -99,6 +108,22 @@ func NewScrapeConfigFake() *ScrapeConfig {
 }
 
 func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int, updateProgressBarName func(index int, name string)) chapter {
+	v := newVisited()
+	v.claim(url)
+
+	c, _ := newChapterFromURL(url, linkName, configs, index, updateProgressBarName, v)
+
+	// the root chapter cannot be dropped
+	if transformed := transformChapters([]chapter{c}); len(transformed) > 0 {
+		c = transformed[0]
+	}
+
+	return c
+}
+
+// newChapterFromURL returns false if the page is already in the book under its canonical URL,
+// the chapter is then a cross reference to it and the links of the page are not followed
+func newChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int, updateProgressBarName func(index int, name string), v *visited) (chapter, bool) {
 	config := configs[0]
 
 	base, err := urllib.Parse(url)
@@ -106,24 +131,24 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 		log.Fatal(err)
 	}
 
-	// get page body
-	response, err := http.Get(url)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer response.Body.Close()
+	var body []byte
+	var page *goquery.Document
+	var article readability.Article
+	metadata := Metadata{Canonical: url}
 
-	// duplicate response stream
-	readabilityReader := &bytes.Buffer{}
-	bodyReader := io.TeeReader(response.Body, readabilityReader)
+	if len(config.JSONLinks) > 0 {
+		// a JSON API only lists the chapters, it is named after its host like in GetLinks
+		article.Title = base.Host
+	} else {
+		var canonical string
+		body, page, article, canonical = fetchPage(base, config)
 
-	// extract HTML body
-	body, err := io.ReadAll(bodyReader)
+		// pages reachable from several URLs declare the one to use
+		if NormalizeHref(canonical) != NormalizeHref(url) && v.claim(canonical) == false {
+			return newCrossReference(canonical, linkName, config), false
+		}
 
-	// extract article content and metadata
-	article, err := readability.FromReader(readabilityReader, base)
-	if err != nil {
-		log.Fatalf("failed to parse %s, %v\n", url, err)
+		metadata = pageMetadata(base, page, article, canonical)
 	}
 
 	name := linkName
@@ -134,15 +159,28 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 		updateProgressBarName(index, name)
 	}
 
+	// let the script fix the chapter name
+	if config.Script != nil {
+		name, err = config.Script.Title(NewChapter(url, string(body), name, article.Byline, "", []chapter{}, config))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		updateProgressBarName(index, name)
+	}
+
 	var subchapters []chapter
 	if len(configs) > 1 {
 
 		// retrieve links on page
-		links, _, _, err := GetLinks(base, config.Selector, config.Limit, config.Offset, config.Reverse, false)
+		links, _, _, err := GetLinks(base, config, false)
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		// do not download pages already in the book tree again
+		links, references := v.filter(base, links, config.CrossReference)
+
 		// init progess bar
 		var p progress
 		if config.Quiet == false {
@@ -151,6 +189,7 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 
 		// init chapters list
 		subchapters = make([]chapter, len(links))
+		claimed := make([]bool, len(links))
 
 		if config.Delay >= 0 {
 
@@ -162,8 +201,16 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 					log.Fatal(err)
 				}
 
-				sc := NewChapterFromURL(u.String(), link.Text, configs[1:], index, p.UpdateName)
-				subchapters[index] = sc
+				if references[index] {
+					subchapters[index] = newCrossReference(u.String(), link.Text, configs[1])
+					claimed[index] = true
+					if config.Quiet == false {
+						p.Increment(index)
+					}
+					continue
+				}
+
+				subchapters[index], claimed[index] = newChapterFromURL(u.String(), link.Text, configs[1:], index, p.UpdateName, v)
 				if config.Quiet == false {
 					p.Increment(index)
 				}
@@ -183,6 +230,20 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 
 			for index, l := range links {
 
+				if references[index] {
+					u, err := base.Parse(l.Href)
+					if err != nil {
+						log.Fatal(err)
+					}
+
+					subchapters[index] = newCrossReference(u.String(), l.Text, configs[1])
+					claimed[index] = true
+					if config.Quiet == false {
+						p.Increment(index)
+					}
+					continue
+				}
+
 				wg.Add(1)
 				semaphore <- true
 
@@ -195,8 +256,7 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 						log.Fatal(err)
 					}
 
-					sc := NewChapterFromURL(u.String(), l.Text, configs[1:], index, p.UpdateName)
-					subchapters[index] = sc
+					subchapters[index], claimed[index] = newChapterFromURL(u.String(), l.Text, configs[1:], index, p.UpdateName, v)
 
 					if config.Quiet == false {
 						p.Increment(index)
@@ -207,30 +267,49 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 			}
 			wg.Wait()
 		}
+
+		// different links may lead to a page already in the book
+		subchapters = dropDuplicates(subchapters, claimed, config.CrossReference)
+
+		// the script may modify or drop chapters
+		subchapters = transformChapters(subchapters)
 	}
 
 	content := ""
-	if config.Include {
+	if config.Include && page != nil {
 
 		// we care about the content only if:
 		// - we include this level
 		// - we use the page name
 
+		extractor := config.Extractor
+		if extractor == nil {
+			extractor = ReadabilityExtractor{}
+		}
+
+		articleContent, err := extractor.Extract(base, page, article)
+		if err != nil {
+			log.Fatal(err)
+		}
+
 		// parse HTML
-		doc, err := goquery.NewDocumentFromReader(strings.NewReader(article.Content))
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(articleContent))
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		// handle lazy images
-		doc.Find("img").Each(func(i int, source *goquery.Selection) {
-			src, exists := source.Attr("data-lazy-src")
-			if exists {
-				source.SetAttr("src", src)
-			}
-		})
+		// images were fixed before extraction, src is the one to download
+		doc.Find("img").RemoveAttr("srcset").RemoveAttr("sizes")
 		doc.Find("source").Remove()
 
+		// remove unwanted elements kept by the extractor
+		for _, selector := range config.RemoveAfter {
+			doc.Find(selector).Remove()
+		}
+
+		// footnotes are shown as popups in EPUB books
+		markFootnotes(doc, base)
+
 		// extract images
 		if config.ImagesOnly {
 
@@ -252,7 +331,82 @@ func NewChapterFromURL(url, linkName string, configs []*ScrapeConfig, index int,
 		}
 
 	}
-	return chapter{url, string(body), name, article.Byline, content, subchapters, config}
+	return chapter{url, string(body), name, article.Byline, content, subchapters, config, metadata, "", 0}, true
+}
+
+// fetchPage downloads the page at base and extracts its article, it returns the page body and its canonical URL too
+func fetchPage(base *urllib.URL, config *ScrapeConfig) ([]byte, *goquery.Document, readability.Article, string) {
+	// get page body
+	response, err := httpClient(config).Get(base.String())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	// duplicate response stream
+	readabilityReader := &bytes.Buffer{}
+	bodyReader := io.TeeReader(response.Body, readabilityReader)
+
+	// extract HTML body
+	body, err := io.ReadAll(bodyReader)
+
+	// pages reachable from several URLs declare the one to use
+	canonical := canonicalURL(base, body)
+
+	// remove unwanted elements before extracting anything
+	page, err := goquery.NewDocumentFromReader(readabilityReader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, selector := range config.Remove {
+		page.Find(selector).Remove()
+	}
+
+	// show the real images to readability and extractors
+	fixImages(page, config.ImageWidth)
+
+	// extract article content and metadata
+	article, err := readability.FromDocument(page.Nodes[0], base)
+	if err != nil {
+		log.Fatalf("failed to parse %s, %v\n", base, err)
+	}
+
+	// javascript websites embed the article in their data, readability only sees an empty shell
+	article = embeddedArticle(base, page, article)
+
+	return body, page, article, canonical
+}
+
+// absoluteURLs resolves the links and sources of the selection and its children against base
+func absoluteURLs(base *urllib.URL, s *goquery.Selection) {
+	for _, attr := range []string{"href", "src"} {
+		s.Find("[" + attr + "]").AddSelection(s.Filter("[" + attr + "]")).Each(func(i int, e *goquery.Selection) {
+			value, _ := e.Attr(attr)
+			if u, err := base.Parse(strings.TrimSpace(value)); err == nil {
+				e.SetAttr(attr, u.String())
+			}
+		})
+	}
+}
+
+// canonicalURL returns the URL declared in <link rel=canonical>, or base if there is none.
+func canonicalURL(base *urllib.URL, body []byte) string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return base.String()
+	}
+
+	href, exists := doc.Find("link[rel=canonical]").Attr("href")
+	if exists == false || len(strings.TrimSpace(href)) == 0 {
+		return base.String()
+	}
+
+	u, err := base.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return base.String()
+	}
+
+	return u.String()
 }
 
 func tableOfContent(url string, config *ScrapeConfig, subConfig *ScrapeConfig, quiet bool) ([]chapter, chapter) {
@@ -261,7 +415,7 @@ func tableOfContent(url string, config *ScrapeConfig, subConfig *ScrapeConfig, q
 		log.Fatal(err)
 	}
 
-	links, _, home, err := GetLinks(base, config.Selector, config.Limit, config.Offset, config.Reverse, config.Include)
+	links, _, home, err := GetLinks(base, config, config.Include)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -354,111 +508,250 @@ func GetPath(elm *goquery.Selection) string {
 	return join
 }
 
-func GetLinks(url *urllib.URL, selector string, limit, offset int, reverse, include bool) ([]link, string, chapter, error) {
-	var links []link
-	var pathMax string
+// selectors starting with this prefix are evaluated as XPath expressions instead of CSS selectors
+const xpathPrefix = "xpath:"
+
+// GetCandidates returns the groups of links found on the page, the most probable table of contents first.
+// If the config selector is set, there is a single group containing the links it selects.
+func GetCandidates(url *urllib.URL, config *ScrapeConfig) ([]candidate, error) {
+	selector := config.Selector
+	selectorSet := true
+	if len(selector) == 0 {
+		selector = "a"
+		if len(config.HrefAttr) > 0 && config.HrefAttr != "href" {
+			selector = fmt.Sprintf("a, [%s]", config.HrefAttr)
+		}
+		selectorSet = false
+	}
 
-	parser := gofeed.NewParser()
-	feed, err := parser.ParseURL(url.String())
+	groups := map[string]*candidate{}
+	keys := []string{}
 
-	if err == nil {
-		// RSS feed
+	// visit and count link classes
+	visit := func(e *goquery.Selection) {
+		text := linkText(e, config)
 
-		for _, item := range feed.Items {
-			u, err := url.Parse(item.Link)
-			if err != nil {
-				log.Fatal(err)
+		u, err := url.Parse(linkHref(e, config))
+		if err != nil {
+			log.Fatal(err)
+		}
+		href := CleanURL(u).String()
+
+		// if selector is set, we use the selector specified by the user
+		key := selector
+		groupSelector := selector
+
+		if selectorSet == false {
+
+			// if selector is not set, we compute the selector ourselves
+
+			// we count this key if the link text is not empty
+			if text == "" {
+				return
 			}
 
-			links = append(links, NewLink(u.String(), item.Title, item.PublishedParsed))
+			path := GetPath(e)
+			class, _ := e.Attr("class")
+			// include the element class to make sure we have the same exact path for every link in the table of content
+			key = fmt.Sprintf("%s.%s", path, class)
+			groupSelector = FormatPath(path, class)
+
 		}
 
-		pathMax = "RSS"
-	} else {
-		// HTML website
+		group, exists := groups[key]
+		if exists == false {
+			group = &candidate{Selector: groupSelector}
+			groups[key] = group
+			keys = append(keys, key)
+		}
 
-		selectorSet := true
-		if len(selector) == 0 {
-			selector = "a"
-			selectorSet = false
+		group.Links = append(group.Links, NewLink(href, text, &time.Time{}))
+		if selectorSet {
+			group.Score += 1
+		} else {
+			group.Score += len(text)
 		}
+	}
 
-		pathLinks := map[string][]link{}
-		pathCount := map[string]int{}
-		pathMax = ""
+	c := colly.NewCollector()
+	if config.Renderer != nil {
+		c.WithTransport(config.Renderer)
+	}
 
-		// visit and count link classes
-		c := colly.NewCollector()
-		c.OnHTML(selector, func(e *colly.HTMLElement) {
-			text := strings.TrimSpace(e.Text)
-			path := GetPath(e.DOM)
-			key := path
+	if strings.HasPrefix(selector, xpathPrefix) {
+		// XPath expression, it must select <a> elements
+		query := strings.TrimPrefix(selector, xpathPrefix)
+		if _, err := xpath.Compile(query); err != nil {
+			return []candidate{}, fmt.Errorf("invalid xpath selector %s: %v", query, err)
+		}
 
-			u, err := url.Parse(e.Attr("href"))
-			if err != nil {
-				log.Fatal(err)
+		c.OnXML(query, func(e *colly.XMLElement) {
+			node, ok := e.DOM.(*html.Node)
+			if ok {
+				visit(goquery.NewDocumentFromNode(node).Selection)
 			}
-			href := u.String()
+		})
+	} else {
+		c.OnHTML(selector, func(e *colly.HTMLElement) {
+			visit(e.DOM)
+		})
+	}
 
-			if selectorSet {
+	err := c.Visit(url.String())
+	if err != nil {
+		return []candidate{}, err
+	}
 
-				// if selector is set, we use the selector specified by the user
+	candidates := []candidate{}
+	for _, key := range keys {
+		candidates = append(candidates, *groups[key])
+	}
 
-				key = selector
-				pathLinks[key] = append(pathLinks[key], NewLink(href, text, &time.Time{}))
-				pathCount[key] += 1
-				pathMax = key
+	// the table of contents is the group with the most link text
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
 
-			} else {
+	return candidates, nil
+}
 
-				// if selector is not set, we compute the selector ourselves
+// linkHref returns the URL of a link element, read from the attribute set in config or from href
+func linkHref(e *goquery.Selection, config *ScrapeConfig) string {
+	if len(config.HrefAttr) > 0 {
+		href, exists := e.Attr(config.HrefAttr)
+		if exists && len(strings.TrimSpace(href)) > 0 {
+			return strings.TrimSpace(href)
+		}
+	}
 
-				class := e.Attr("class")
-				// include the element class to make sure we have the same exact path for every link in the table of content
-				key = fmt.Sprintf("%s.%s", path, class)
+	href, _ := e.Attr("href")
+	return strings.TrimSpace(href)
+}
 
-				// we count this key if the link text is not empty
-				if text != "" {
-					pathLinks[key] = append(pathLinks[key], NewLink(href, text, &time.Time{}))
-					pathCount[key] += len(text)
+// linkText returns the title of a link element, read from the child element or attribute set in config or from its text
+func linkText(e *goquery.Selection, config *ScrapeConfig) string {
+	if len(config.TitleSelector) > 0 {
+		title := e.Find(config.TitleSelector).First()
+		if title.Length() > 0 && len(strings.TrimSpace(title.Text())) > 0 {
+			return strings.TrimSpace(title.Text())
+		}
+	}
 
-					if pathCount[key] > pathCount[pathMax] {
-						pathMax = key
-					}
-				}
+	if len(config.TitleAttr) > 0 {
+		title, exists := e.Attr(config.TitleAttr)
+		if exists && len(strings.TrimSpace(title)) > 0 {
+			return strings.TrimSpace(title)
+		}
+	}
+
+	return strings.TrimSpace(e.Text())
+}
+
+// GetLinks returns the links of the table of contents at url, the selector path used to find them and the page itself.
+func GetLinks(url *urllib.URL, config *ScrapeConfig, include bool) ([]link, string, chapter, error) {
+	var links []link
+	var path string
+
+	selector := config.Selector
 
+	var err error
+	parser := gofeed.NewParser()
+
+	if len(config.JSONLinks) > 0 {
+		// JSON API
+
+		links, err = getJSONLinks(url, config)
+		if err != nil {
+			return []link{}, "JSON", chapter{}, err
+		}
+
+		path = "JSON"
+	} else if feed, err := parser.ParseURL(url.String()); err == nil {
+		// RSS feed
+
+		for _, item := range feed.Items {
+			u, err := url.Parse(item.Link)
+			if err != nil {
+				log.Fatal(err)
 			}
-		})
-		c.Visit(url.String())
 
-		links = pathLinks[pathMax]
+			links = append(links, NewLink(CleanURL(u).String(), item.Title, item.PublishedParsed))
+		}
+
+		path = "RSS"
+	} else {
+		// HTML website
+
+		candidates, err := GetCandidates(url, config)
+		if err != nil {
+			return []link{}, "", chapter{}, err
+		}
+
+		if config.Candidate >= len(candidates) && len(candidates) > 0 {
+			return []link{}, "", chapter{}, fmt.Errorf("candidate %d not found, only %d candidates on page", config.Candidate+1, len(candidates))
+		}
+
+		if len(candidates) > 0 {
+			links = candidates[config.Candidate].Links
+			path = candidates[config.Candidate].Selector
+		}
 	}
 
+	// let the script compute the links
+	if config.Script != nil {
+		links, err = config.Script.Links(url.String(), links)
+		if err != nil {
+			return []link{}, path, chapter{}, err
+		}
+	}
+
+	// a table of contents often links the same chapter several times
+	links = dedupeLinks(links)
+
 	if len(links) == 0 {
-		return []link{}, pathMax, chapter{}, fmt.Errorf("no link found for selector: %s", selector)
+		return []link{}, path, chapter{}, fmt.Errorf("no link found for selector: %s", selector)
 	}
 
+	// order links before selecting them, so the last chapters are the newest ones when sorting by date
+	sortLinks(links, config.Sort)
+
+	// keep selected chapters only
+	links, err = selectChapters(links, config.Chapters)
+	if err != nil {
+		return []link{}, path, chapter{}, err
+	}
+
+	offset := int(math.Min(float64(config.Offset), float64(len(links))))
 	end := len(links)
-	if limit != -1 {
-		end = int(math.Min(float64(limit+offset), float64(len(links))))
+	if config.Limit != -1 {
+		end = int(math.Min(float64(config.Limit+offset), float64(len(links))))
 	}
 
 	links = links[offset:end]
 
-	home := NewChapterFromURL(url.String(), "", []*ScrapeConfig{NewScrapeConfig()}, 0, func(index int, name string) {})
+	var home chapter
+	if path == "JSON" {
+		// a JSON document is not a page, it is named after its host and never included
+		home = NewChapter(url.String(), "", url.Host, "", "", []chapter{}, NewScrapeConfigNoInclude())
+		include = false
+	} else {
+		homeConfig := NewScrapeConfig()
+		homeConfig.Renderer = config.Renderer
+		home = NewChapterFromURL(url.String(), "", []*ScrapeConfig{homeConfig}, 0, func(index int, name string) {})
+	}
 
 	// include home page
 	if include {
 		l := NewLink(url.String(), home.Name(), &time.Time{})
-		links = append([]link{l}, links...)
+		links = dedupeLinks(append([]link{l}, links...))
 	}
 
 	// reverse links
-	if reverse {
+	if config.Reverse {
 		for i, j := 0, len(links)-1; i < j; i, j = i+1, j-1 {
 			links[i], links[j] = links[j], links[i]
 		}
 	}
 
-	return links, pathMax, home, nil
+	return links, path, home, nil
 }
@@ -1,6 +1,10 @@
 package book
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	urllib "net/url"
 	"testing"
 	"time"
 )
@@ -234,3 +238,109 @@ func TestNotInclude(t *testing.T) {
 	}
 
 }
+
+func TestGetLinksXPath(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Index</title></head><body>
+			<h2>News</h2><ul><li><a href="/news">News</a></li></ul>
+			<h2>Chapters</h2><ul><li><a href="/1">One</a></li><li><a href="/2">Two</a></li></ul>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	base, _ := urllib.Parse(server.URL)
+	config := NewScrapeConfig()
+	config.Selector = "xpath://h2[contains(., 'Chapters')]/following-sibling::ul[1]//a"
+
+	links, _, _, err := GetLinks(base, config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := len(links)
+	want := 2
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestGetCandidates(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>
+			<nav><a href="/">Home</a><a href="/about">About</a></nav>
+			<ul class="toc"><li><a class="chapter" href="/1">The first chapter</a></li><li><a class="chapter" href="/2">The second chapter</a></li></ul>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	base, _ := urllib.Parse(server.URL)
+	candidates, err := GetCandidates(base, NewScrapeConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(candidates) != 2 {
+		t.Fatalf("got %v candidates, wanted %v", len(candidates), 2)
+	}
+
+	got := candidates[0].Selector
+	want := "html>body>ul>li>a.chapter"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestGetLinksAttributes(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><ul>
+			<li class="chapter" data-href="/1" title="Chapter 1"><span class="number">1</span><span class="name">The Beginning</span></li>
+			<li class="chapter" data-href="/2" title="Chapter 2"><span class="number">2</span><span class="name">The End</span></li>
+		</ul></body></html>`)
+	}))
+	defer server.Close()
+
+	base, _ := urllib.Parse(server.URL)
+
+	config := NewScrapeConfig()
+	config.Selector = "li.chapter"
+	config.HrefAttr = "data-href"
+	config.TitleSelector = ".name"
+
+	links, _, _, err := GetLinks(base, config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 2 {
+		t.Fatalf("got %v links, wanted %v", len(links), 2)
+	}
+	if links[1].Href != server.URL+"/2" || links[1].Text != "The End" {
+		t.Errorf("got %v, wanted %v", links[1], "The End")
+	}
+
+	config.TitleSelector = ""
+	config.TitleAttr = "title"
+
+	links, _, _, err = GetLinks(base, config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := links[0].Text
+	want := "Chapter 1"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
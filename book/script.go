@@ -0,0 +1,266 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// maximum number of computation steps of a hook, so a script cannot hang the scraper
+const scriptMaxSteps = 100000000
+
+// Script holds the hooks of a Starlark file:
+// links(page) returns the links of a table of contents,
+// title(chapter) returns the chapter name,
+// transform(chapter) returns the modified chapter or None to drop it
+type Script struct {
+	filename string
+	globals  starlark.StringDict
+}
+
+// LoadScript executes a Starlark file and keeps its hooks.
+// Scripts only have access to the Starlark built-ins, load statements are not allowed.
+func LoadScript(filename string) (*Script, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Script{filename: filename}
+
+	globals, err := starlark.ExecFile(s.thread(), filename, src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// hooks are called from several goroutines
+	globals.Freeze()
+	s.globals = globals
+
+	for _, hook := range []string{"links", "title", "transform"} {
+		if value, exists := globals[hook]; exists {
+			if _, callable := value.(starlark.Callable); callable == false {
+				return nil, fmt.Errorf("%s: %s is not a function", filename, hook)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// thread returns a new interpreter thread, without access to the filesystem or the network
+func (s *Script) thread() *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: s.filename,
+		Print: func(thread *starlark.Thread, msg string) {
+			fmt.Fprintln(os.Stderr, msg)
+		},
+		Load: func(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, errors.New("load is not allowed in scripts")
+		},
+	}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+
+	return thread
+}
+
+// call calls hook with arg, the boolean is false if the script does not define hook
+func (s *Script) call(hook string, arg starlark.Value) (starlark.Value, bool, error) {
+	fn, exists := s.globals[hook]
+	if exists == false {
+		return starlark.None, false, nil
+	}
+
+	result, err := starlark.Call(s.thread(), fn, starlark.Tuple{arg}, nil)
+	if err != nil {
+		return starlark.None, true, fmt.Errorf("%s hook: %v", hook, err)
+	}
+
+	return result, true, nil
+}
+
+// Links passes the links of the table of contents at url to the links hook and returns the ones it returns
+func (s *Script) Links(url string, links []link) ([]link, error) {
+	list := make([]starlark.Value, len(links))
+	for index, l := range links {
+		list[index] = linkDict(l)
+	}
+
+	page := starlark.NewDict(2)
+	page.SetKey(starlark.String("url"), starlark.String(url))
+	page.SetKey(starlark.String("links"), starlark.NewList(list))
+
+	result, exists, err := s.call("links", page)
+	if err != nil || exists == false {
+		return links, err
+	}
+
+	iterable, ok := result.(starlark.Iterable)
+	if ok == false {
+		return links, fmt.Errorf("links hook: got %s, wanted a list", result.Type())
+	}
+
+	newLinks := []link{}
+	iterator := iterable.Iterate()
+	defer iterator.Done()
+
+	var item starlark.Value
+	for iterator.Next(&item) {
+		switch item := item.(type) {
+
+		// url only
+		case starlark.String:
+			newLinks = append(newLinks, NewLink(string(item), string(item), nil))
+
+		case *starlark.Dict:
+			href := dictString(item, "url")
+			if len(href) == 0 {
+				return links, errors.New("links hook: link without url")
+			}
+
+			text := dictString(item, "name")
+			if len(text) == 0 {
+				text = href
+			}
+
+			newLinks = append(newLinks, NewLink(href, text, dictDate(item, "date")))
+
+		default:
+			return links, fmt.Errorf("links hook: got %s, wanted a string or a dict", item.Type())
+		}
+	}
+
+	return newLinks, nil
+}
+
+// Title returns the name given by the title hook, or name if there is no hook
+func (s *Script) Title(c chapter) (string, error) {
+	result, exists, err := s.call("title", chapterDict(c))
+	if err != nil || exists == false {
+		return c.name, err
+	}
+
+	title, ok := starlark.AsString(result)
+	if ok == false {
+		return c.name, fmt.Errorf("title hook: got %s, wanted a string", result.Type())
+	}
+
+	return title, nil
+}
+
+// Transform returns the chapter modified by the transform hook, the boolean is false if the chapter is dropped
+func (s *Script) Transform(c chapter) (chapter, bool, error) {
+	result, exists, err := s.call("transform", chapterDict(c))
+	if err != nil || exists == false {
+		return c, true, err
+	}
+
+	if result == starlark.None {
+		return c, false, nil
+	}
+
+	dict, ok := result.(*starlark.Dict)
+	if ok == false {
+		return c, true, fmt.Errorf("transform hook: got %s, wanted a dict or None", result.Type())
+	}
+
+	// missing keys keep the chapter values
+	c.name = dictStringOr(dict, "name", c.name)
+	c.author = dictStringOr(dict, "author", c.author)
+	c.content = dictStringOr(dict, "content", c.content)
+
+	return c, true, nil
+}
+
+// transformChapters applies the transform hook of each chapter config, dropped chapters are removed
+func transformChapters(chapters []chapter) []chapter {
+	kept := []chapter{}
+
+	for _, c := range chapters {
+		if c.config == nil || c.config.Script == nil {
+			kept = append(kept, c)
+			continue
+		}
+
+		c, keep, err := c.config.Script.Transform(c)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if keep {
+			kept = append(kept, c)
+		}
+	}
+
+	return kept
+}
+
+func linkDict(l link) *starlark.Dict {
+	// dates are given as RFC 3339 strings, None if the link has no date
+	var date starlark.Value = starlark.None
+	if l.Date != nil && l.Date.IsZero() == false {
+		date = starlark.String(l.Date.Format(time.RFC3339))
+	}
+
+	dict := starlark.NewDict(3)
+	dict.SetKey(starlark.String("url"), starlark.String(l.Href))
+	dict.SetKey(starlark.String("name"), starlark.String(l.Text))
+	dict.SetKey(starlark.String("date"), date)
+
+	return dict
+}
+
+func chapterDict(c chapter) *starlark.Dict {
+	depth := 0
+	if c.config != nil {
+		depth = c.config.Depth
+	}
+
+	dict := starlark.NewDict(5)
+	dict.SetKey(starlark.String("url"), starlark.String(c.url))
+	dict.SetKey(starlark.String("name"), starlark.String(c.name))
+	dict.SetKey(starlark.String("author"), starlark.String(c.author))
+	dict.SetKey(starlark.String("content"), starlark.String(c.content))
+	dict.SetKey(starlark.String("depth"), starlark.MakeInt(depth))
+
+	return dict
+}
+
+// dictString returns the string value of key, or an empty string
+func dictString(dict *starlark.Dict, key string) string {
+	return dictStringOr(dict, key, "")
+}
+
+// dictStringOr returns the string value of key, or fallback if the dict has no such key
+func dictStringOr(dict *starlark.Dict, key, fallback string) string {
+	value, found, err := dict.Get(starlark.String(key))
+	if err != nil || found == false {
+		return fallback
+	}
+
+	s, _ := starlark.AsString(value)
+	return s
+}
+
+// dictDate parses the date string or UNIX timestamp of key, nil if the dict has no date
+func dictDate(dict *starlark.Dict, key string) *time.Time {
+	value, found, err := dict.Get(starlark.String(key))
+	if err != nil || found == false {
+		return nil
+	}
+
+	switch value := value.(type) {
+	case starlark.String:
+		if len(value) > 0 {
+			return parseJSONDate(string(value))
+		}
+	case starlark.Int:
+		return parseJSONDate(value.String())
+	}
+
+	return nil
+}
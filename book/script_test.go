@@ -0,0 +1,183 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	urllib "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript saves src in a temporary Starlark file and loads it
+func writeScript(t *testing.T, src string) *Script {
+	filename := filepath.Join(t.TempDir(), "hooks.star")
+	if err := os.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadScript(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func newScriptServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><head><title>Index</title></head><body><ul>
+				<li><a href="/1">Chapter 1</a></li><li><a href="/ads">Sponsored</a></li><li><a href="/2">Chapter 2</a></li>
+			</ul></body></html>`)
+			return
+		}
+
+		fmt.Fprintf(w, `<html><head><title>Page %s</title></head><body><p>Content of page %s.</p></body></html>`, r.URL.Path, r.URL.Path)
+	}))
+}
+
+func TestScriptLinks(t *testing.T) {
+
+	server := newScriptServer()
+	defer server.Close()
+
+	base, _ := urllib.Parse(server.URL)
+	config := NewScrapeConfig()
+	config.Script = writeScript(t, `
+def links(page):
+    kept = [l for l in page["links"] if not l["url"].endswith("/ads")]
+    return kept + [page["url"] + "/3"]
+`)
+
+	links, _, _, err := GetLinks(base, config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, l := range links {
+		got = append(got, strings.TrimPrefix(l.Href, server.URL))
+	}
+	want := []string{"/1", "/2", "/3"}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestScriptLinksDates(t *testing.T) {
+
+	date := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	links := []link{NewLink("/1", "One", &date), NewLink("/2", "Two", nil)}
+
+	// dates are passed to the hook and read from the links it returns
+	script := writeScript(t, `
+def links(page):
+    dated = [l for l in page["links"] if l["date"] != None]
+    return dated + [{"url": "/3", "name": "Three", "date": "2022-01-02T00:00:00Z"}, {"url": "/4", "date": 1700000000}, {"url": "/5"}]
+`)
+
+	links, err := script.Links("https://example.com", links)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, l := range links {
+		if l.Date == nil {
+			got = append(got, l.Href+" nil")
+		} else {
+			got = append(got, l.Href+" "+l.Date.UTC().Format(time.RFC3339))
+		}
+	}
+	want := []string{"/1 2021-03-04T05:06:07Z", "/3 2022-01-02T00:00:00Z", "/4 2023-11-14T22:13:20Z", "/5 nil"}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestScriptTitleTransform(t *testing.T) {
+
+	server := newScriptServer()
+	defer server.Close()
+
+	script := writeScript(t, `
+def title(chapter):
+    return chapter["name"].upper()
+
+def transform(chapter):
+    if chapter["url"].endswith("/ads"):
+        return None
+    chapter["content"] = chapter["content"].replace("Content", "Text")
+    return chapter
+`)
+
+	config0 := NewScrapeConfigNoInclude()
+	config0.Selector = "ul a"
+	config0.Script = script
+	config1 := NewScrapeConfig()
+	config1.Script = script
+
+	c := NewChapterFromURL(server.URL, "", []*ScrapeConfig{config0, config1}, 0, func(index int, name string) {})
+
+	got := []string{}
+	for _, sc := range c.SubChapters() {
+		got = append(got, sc.Name())
+
+		if strings.Contains(sc.Content(), "Text of page") == false {
+			t.Errorf("got %v, wanted transformed content", sc.Content())
+		}
+	}
+	want := []string{"PAGE /1", "PAGE /2"}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestScriptTransformPartial(t *testing.T) {
+
+	// keys missing from the returned dict keep the chapter values
+	script := writeScript(t, `
+def transform(chapter):
+    return {"name": chapter["name"] + " (draft)"}
+`)
+
+	c := NewChapter("https://example.com/1", "<html></html>", "One", "Ann", "<p>Text</p>", []chapter{}, NewScrapeConfig())
+
+	c, keep, err := script.Transform(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := fmt.Sprint(keep, c.Name(), c.Author(), c.Content())
+	want := fmt.Sprint(true, "One (draft)", "Ann", "<p>Text</p>")
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
+
+func TestScriptSandbox(t *testing.T) {
+
+	filename := filepath.Join(t.TempDir(), "hooks.star")
+	if err := os.WriteFile(filename, []byte(`load("os.star", "read")`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadScript(filename); err == nil {
+		t.Errorf("got no error, wanted load to be rejected")
+	}
+
+}
@@ -0,0 +1,111 @@
+package book
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SortOrders are the valid values of the Sort config, an empty value keeps the document order
+var SortOrders = []string{"date", "title", "natural", "url"}
+
+// CheckSort returns an error if order is not a valid sort order
+func CheckSort(order string) error {
+	if len(order) == 0 {
+		return nil
+	}
+
+	for _, o := range SortOrders {
+		if order == o {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid sort order: %s, use one of %s", order, strings.Join(SortOrders, ", "))
+}
+
+// sortLinks sorts links in place, links without date are kept last in their document order when sorting by date
+func sortLinks(links []link, order string) {
+	var less func(a, b link) bool
+
+	switch order {
+	case "date":
+		less = func(a, b link) bool {
+			if a.Date == nil || a.Date.IsZero() {
+				return false
+			}
+			if b.Date == nil || b.Date.IsZero() {
+				return true
+			}
+			return a.Date.Before(*b.Date)
+		}
+	case "title":
+		less = func(a, b link) bool {
+			return strings.ToLower(a.Text) < strings.ToLower(b.Text)
+		}
+	case "natural":
+		less = func(a, b link) bool {
+			return naturalLess(strings.ToLower(a.Text), strings.ToLower(b.Text))
+		}
+	case "url":
+		less = func(a, b link) bool {
+			return a.Href < b.Href
+		}
+	default:
+		return
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return less(links[i], links[j])
+	})
+}
+
+// naturalLess compares strings with their numbers by value, so "Chapter 2" comes before "Chapter 10"
+func naturalLess(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+
+	for len(ra) > 0 && len(rb) > 0 {
+		if unicode.IsDigit(ra[0]) && unicode.IsDigit(rb[0]) {
+			na, nb := digits(ra), digits(rb)
+
+			// compare numbers without leading zeros, longer is greater
+			va, vb := trimZeros(ra[:na]), trimZeros(rb[:nb])
+			if len(va) != len(vb) {
+				return len(va) < len(vb)
+			}
+			if string(va) != string(vb) {
+				return string(va) < string(vb)
+			}
+
+			ra, rb = ra[na:], rb[nb:]
+			continue
+		}
+
+		if ra[0] != rb[0] {
+			return ra[0] < rb[0]
+		}
+
+		ra, rb = ra[1:], rb[1:]
+	}
+
+	return len(ra) < len(rb)
+}
+
+// digits returns the number of leading digits of r
+func digits(r []rune) int {
+	n := 0
+	for n < len(r) && unicode.IsDigit(r[n]) {
+		n++
+	}
+
+	return n
+}
+
+func trimZeros(r []rune) []rune {
+	for len(r) > 1 && r[0] == '0' {
+		r = r[1:]
+	}
+
+	return r
+}
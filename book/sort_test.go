@@ -0,0 +1,68 @@
+package book
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSortLinks(t *testing.T) {
+
+	titles := []string{"Chapter 10", "chapter 2", "Appendix", "Chapter 1", "Chapter 02b"}
+
+	tests := map[string]string{
+		"":        "[Chapter 10 chapter 2 Appendix Chapter 1 Chapter 02b]",
+		"title":   "[Appendix Chapter 02b Chapter 1 Chapter 10 chapter 2]",
+		"natural": "[Appendix Chapter 1 chapter 2 Chapter 02b Chapter 10]",
+	}
+
+	for order, want := range tests {
+		links := []link{}
+		for _, title := range titles {
+			links = append(links, NewLink("https://example.com/", title, nil))
+		}
+
+		sortLinks(links, order)
+
+		texts := []string{}
+		for _, l := range links {
+			texts = append(texts, l.Text)
+		}
+
+		got := fmt.Sprint(texts)
+		if got != want {
+			t.Errorf("%s: got %v, wanted %v", order, got, want)
+		}
+	}
+
+}
+
+func TestSortLinksDate(t *testing.T) {
+
+	day := func(d int) *time.Time {
+		date := time.Date(2022, 1, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	links := []link{
+		NewLink("https://example.com/pinned", "Pinned", nil),
+		NewLink("https://example.com/3", "Third", day(3)),
+		NewLink("https://example.com/1", "First", day(1)),
+		NewLink("https://example.com/2", "Second", day(2)),
+	}
+
+	sortLinks(links, "date")
+
+	texts := []string{}
+	for _, l := range links {
+		texts = append(texts, l.Text)
+	}
+
+	got := fmt.Sprint(texts)
+	want := "[First Second Third Pinned]"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
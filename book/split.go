@@ -0,0 +1,136 @@
+package book
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	xhtml "golang.org/x/net/html"
+)
+
+// section is a part of a chapter content starting at a heading
+type section struct {
+	name    string
+	id      string
+	content string
+}
+
+// SplitChapters divides the content of every included chapter into subchapters at the elements matching selector, such as h2.
+// The content before the first heading stays in the chapter, the sections come before its other subchapters.
+func SplitChapters(c chapter, selector string) chapter {
+	subChapters := make([]chapter, len(c.subChapters))
+	for index, sc := range c.subChapters {
+		subChapters[index] = SplitChapters(sc, selector)
+	}
+	c.subChapters = subChapters
+
+	if c.config.Include == false || c.config.ImagesOnly || len(c.content) == 0 {
+		return c
+	}
+
+	intro, sections := splitContent(c.content, selector)
+	if len(sections) == 0 {
+		return c
+	}
+
+	parts := []chapter{}
+	for _, s := range sections {
+		url := c.url
+		if len(s.id) > 0 {
+			url = strings.Split(c.url, "#")[0] + "#" + s.id
+		}
+
+		parts = append(parts, chapter{url, c.body, s.name, c.author, s.content, []chapter{}, c.config, c.metadata, "", 0})
+	}
+
+	c.content = intro
+	c.subChapters = append(parts, c.subChapters...)
+	c.sections = len(parts)
+
+	return c
+}
+
+// splitContent returns the content before the first element matching selector, and the sections starting at each one
+func splitContent(content, selector string) (string, []section) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	container := doc.Find("body")
+	headings := container.Find(selector)
+	if headings.Length() == 0 {
+		return content, []section{}
+	}
+
+	// go down wrappers such as <article> holding the whole content
+	for {
+		children := container.Children()
+		if children.Length() != 1 || children.Is(selector) || strings.TrimSpace(children.Text()) != strings.TrimSpace(container.Text()) {
+			break
+		}
+		container = children
+	}
+
+	// the heading of a section may be nested in an element of the container, such as <section><h2>
+	headingOf := map[*xhtml.Node]*goquery.Selection{}
+	headings.Each(func(i int, h *goquery.Selection) {
+		start := h
+		if h.Parent().IsSelection(container) == false {
+			start = h.ParentsUntilSelection(container).Last()
+		}
+
+		if _, exists := headingOf[start.Get(0)]; exists == false {
+			headingOf[start.Get(0)] = h
+		}
+	})
+
+	intro := ""
+	sections := []section{}
+	headingIDs := map[int]string{}
+	container.Contents().Each(func(i int, node *goquery.Selection) {
+		h, isStart := headingOf[node.Get(0)]
+		if isStart {
+			name := strings.Join(strings.Fields(h.Text()), " ")
+
+			// the id of the heading, often set on an element inside it, stays in the section for links to it
+			headingID := h.AttrOr("id", h.Find("[id]").AttrOr("id", ""))
+			id := headingID
+			if len(id) == 0 {
+				id = node.AttrOr("id", "")
+			}
+			sections = append(sections, section{name, id, ""})
+
+			if len(headingID) > 0 {
+				headingIDs[len(sections)-1] = headingID
+			}
+
+			// chapter titles replace the headings
+			if node.Get(0) == h.Get(0) {
+				return
+			}
+			node = node.Clone()
+			node.Find(selector).First().Remove()
+		}
+
+		element, err := goquery.OuterHtml(node)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(sections) == 0 {
+			intro += element
+		} else {
+			sections[len(sections)-1].content += element
+		}
+	})
+
+	// the section is wrapped in an element holding the id of its heading
+	for index, id := range headingIDs {
+		sections[index].content = fmt.Sprintf(`<div id="%s">%s</div>`, html.EscapeString(id), sections[index].content)
+	}
+
+	return intro, sections
+}
@@ -0,0 +1,105 @@
+package book
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSplitContent(t *testing.T) {
+
+	for _, test := range []struct {
+		content string
+		want    string
+	}{
+		{`<div><p>Intro</p><h2 id="one">One</h2><p>First</p><h2>Two <em>bis</em></h2><p>Second</p></div>`, `<p>Intro</p> [{One one <div id="one"><p>First</p></div>} {Two bis  <p>Second</p>}]`},
+		{`<article><section id="a"><h2>A</h2><p>First</p></section><section><h2>B</h2><p>Second</p></section></article>`, ` [{A a <section id="a"><p>First</p></section>} {B  <section><p>Second</p></section>}]`},
+		{`<h2><span id="a">A</span></h2><p>First</p><section><h2 id="b">B</h2><p>Second</p></section>`, ` [{A a <div id="a"><p>First</p></div>} {B b <div id="b"><section><p>Second</p></section></div>}]`},
+		{`<p>No heading</p>`, `<p>No heading</p> []`},
+	} {
+		intro, sections := splitContent(test.content, "h2")
+
+		got := fmt.Sprintf("%s %v", intro, sections)
+
+		if got != test.want {
+			t.Errorf("got %v, wanted %v", got, test.want)
+		}
+	}
+
+}
+
+func TestSplitChapters(t *testing.T) {
+
+	c := NewEmptyChapter()
+	c.AddSubChapter(NewChapter("https://example.com/manual", "<html></html>", "Manual", "", `<p>Intro, see <a href="#usage">usage</a></p><h2 id="install">Install</h2><p>First</p><h2 id="usage">Usage</h2><p>Second</p>`, []chapter{}, NewScrapeConfig()))
+	c.AddSubChapter(NewChapter("https://example.com/faq", "<html></html>", "FAQ", "", `<p>Read the <a href="https://example.com/manual#usage">usage</a></p>`, []chapter{}, NewScrapeConfig()))
+
+	c = SplitChapters(c, "h2")
+
+	manual := c.SubChapters()[0]
+	got := fmt.Sprint(len(manual.SubChapters()), manual.SubChapters()[0].Name(), manual.SubChapters()[0].Url(), manual.SubChapters()[1].Content())
+	want := `2Installhttps://example.com/manual#install<div id="usage"><p>Second</p></div>`
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	// links to a section lead to its file, from the same page or from another one
+	c = LinkChapters(c, "epub")
+
+	for _, got := range []string{c.SubChapters()[0].Content(), c.SubChapters()[1].Content()} {
+		want := `href="chapter0003.xhtml#usage"`
+
+		if strings.Contains(got, want) == false {
+			t.Errorf("got %v, wanted %v", got, want)
+		}
+	}
+
+}
+
+func TestSplitChaptersEpub(t *testing.T) {
+
+	// sections are nested under their chapter in the table of contents, at any depth
+	volume := NewChapter("https://example.com/volume", "<html></html>", "Volume", "", "<p>Volume</p><h2>Preface</h2><p>Text</p>", []chapter{}, NewScrapeConfig())
+	volume.AddSubChapter(NewChapter("https://example.com/manual", "<html></html>", "Manual", "", `<p>Intro</p><h2>Install</h2><p>First</p><h2>Usage</h2><p>Second</p>`, []chapter{}, NewScrapeConfig()))
+
+	c := NewEmptyChapter()
+	c.SetName("Manual")
+	c.AddSubChapter(volume)
+
+	c = LinkChapters(SplitChapters(c, "h2"), "epub")
+	filename := ToEpub(c, filepath.Join(t.TempDir(), "manual.epub"))
+
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	nav := ""
+	for _, f := range r.File {
+		if strings.HasSuffix(f.Name, "nav.xhtml") {
+			file, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			nav = regexp.MustCompile(`>\s+<`).ReplaceAllString(string(data), "><")
+		}
+	}
+
+	want := `<a href="xhtml/chapter0003.xhtml">Manual</a><ol><li><a href="xhtml/chapter0004.xhtml">Install</a></li><li><a href="xhtml/chapter0005.xhtml">Usage</a></li></ol>`
+
+	if strings.Contains(nav, want) == false {
+		t.Errorf("got %v, wanted %v", nav, want)
+	}
+
+}
@@ -0,0 +1,90 @@
+package book
+
+import (
+	"log"
+	urllib "net/url"
+	"time"
+)
+
+// LinkNode is a link of a table of contents along with the table of contents of the page it points to
+type LinkNode struct {
+	link
+	Chapters []LinkNode  `json:"chapters,omitempty"`
+	Status   *LinkStatus `json:"status,omitempty"` // set by CheckLinkTree
+}
+
+// GetLinkTree returns the table of contents at url, walking one level per config like NewChapterFromURL does, without downloading the chapters content.
+// maxFetch bounds the number of tables of contents retrieved, -1 means no limit.
+func GetLinkTree(url *urllib.URL, configs []*ScrapeConfig, include bool, maxFetch int) ([]LinkNode, string, chapter, error) {
+	v := newVisited()
+	v.claim(url.String())
+
+	fetched := 1
+	links, path, home, err := GetLinks(url, configs[0], include)
+	if err != nil {
+		return []LinkNode{}, path, home, err
+	}
+
+	nodes := linkTreeLevel(url, links, configs, v, &fetched, maxFetch)
+
+	return nodes, path, home, nil
+}
+
+// linkTreeLevel turns the links found at base into nodes, retrieving the table of contents of each link if there is a deeper level
+func linkTreeLevel(base *urllib.URL, links []link, configs []*ScrapeConfig, v *visited, fetched *int, maxFetch int) []LinkNode {
+	config := configs[0]
+
+	// the included page is already in the book
+	self := NormalizeURL(base)
+
+	// claim every link of the level first, like NewChapterFromURL does
+	nodes := []LinkNode{}
+	for _, l := range links {
+		u, err := base.Parse(l.Href)
+		if err != nil {
+			log.Fatal(err)
+		}
+		l.Href = u.String()
+
+		if NormalizeURL(u) != self && v.claim(u.String()) == false {
+			continue
+		}
+
+		nodes = append(nodes, LinkNode{l, nil, nil})
+	}
+
+	// the last config is used to retrieve chapters content, not links
+	if len(configs) <= 2 {
+		return nodes
+	}
+
+	for index, node := range nodes {
+		if NormalizeHref(node.Href) == self {
+			continue
+		}
+
+		if maxFetch != -1 && *fetched >= maxFetch {
+			break
+		}
+
+		u, err := urllib.Parse(node.Href)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		*fetched += 1
+		children, _, _, err := GetLinks(u, configs[1], false)
+		if err != nil {
+			// a page without table of contents is a leaf
+			continue
+		}
+
+		nodes[index].Chapters = linkTreeLevel(u, children, configs[1:], v, fetched, maxFetch)
+
+		if config.Delay > 0 {
+			time.Sleep(time.Duration(config.Delay) * time.Millisecond)
+		}
+	}
+
+	return nodes
+}
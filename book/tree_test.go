@@ -0,0 +1,54 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	urllib "net/url"
+	"testing"
+)
+
+func TestGetLinkTree(t *testing.T) {
+
+	pages := map[string]string{
+		"/":  `<a class="toc" href="/1">One</a><a class="toc" href="/2">Two</a>`,
+		"/1": `<a class="toc" href="/">Index</a><a class="toc" href="/1/a">One A</a><a class="toc" href="/2">Two</a>`,
+		"/2": `<a class="toc" href="/2/a">Two A</a>`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body>%s</body></html>", r.URL.Path, pages[r.URL.Path])
+	}))
+	defer server.Close()
+
+	configs := NewScrapeConfigs([]string{"a.toc", "a.toc", ""})
+
+	base, _ := urllib.Parse(server.URL + "/")
+	nodes, _, _, err := GetLinkTree(base, configs, false, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("got %v links, wanted %v", len(nodes), 2)
+	}
+
+	// links back to the index and to siblings are already in the tree
+	got := len(nodes[0].Chapters)
+	want := 1
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	nodes, _, _, err = GetLinkTree(base, configs, false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes[1].Chapters) != 0 {
+		t.Errorf("got %v, wanted no chapters after max fetch", len(nodes[1].Chapters))
+	}
+
+}
@@ -0,0 +1,87 @@
+package book
+
+import (
+	"fmt"
+	"html"
+	urllib "net/url"
+	"sync"
+)
+
+// visited records the pages already in the book tree, it is shared by every level of a scrape
+type visited struct {
+	mu    sync.Mutex
+	pages map[string]bool
+}
+
+func newVisited() *visited {
+	return &visited{pages: map[string]bool{}}
+}
+
+// claim marks url as part of the book, it returns false if url was already claimed
+func (v *visited) claim(url string) bool {
+	key := NormalizeHref(url)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.pages[key] {
+		return false
+	}
+	v.pages[key] = true
+
+	return true
+}
+
+// filter claims every link and drops the ones already in the book.
+// If crossReference is set, visited links are kept and flagged in the returned slice instead.
+func (v *visited) filter(base *urllib.URL, links []link, crossReference bool) ([]link, []bool) {
+	kept := []link{}
+	references := []bool{}
+
+	for _, l := range links {
+		u, err := base.Parse(l.Href)
+		if err != nil {
+			continue
+		}
+
+		if v.claim(u.String()) {
+			kept = append(kept, l)
+			references = append(references, false)
+		} else if crossReference {
+			kept = append(kept, l)
+			references = append(references, true)
+		}
+	}
+
+	return kept, references
+}
+
+// newCrossReference returns a chapter pointing to a page already present elsewhere in the book
+func newCrossReference(url, name string, config *ScrapeConfig) chapter {
+	if len(name) == 0 {
+		name = url
+	}
+
+	// the reference is always displayed, even if the level content is not included
+	referenceConfig := *config
+	referenceConfig.Include = true
+	referenceConfig.ImagesOnly = false
+
+	content := fmt.Sprintf("<p>See <a href=\"%s\">%s</a>.</p>", html.EscapeString(url), html.EscapeString(name))
+
+	return chapter{url, "", name, "", content, []chapter{}, &referenceConfig, Metadata{Canonical: url}, "", 0}
+}
+
+// dropDuplicates removes the chapters which were not claimed, as their page is already in the book under another URL.
+// If crossReference is set, they are kept as cross references instead.
+func dropDuplicates(chapters []chapter, claimed []bool, crossReference bool) []chapter {
+	kept := []chapter{}
+
+	for index, c := range chapters {
+		if claimed[index] || crossReference {
+			kept = append(kept, c)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,89 @@
+package book
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	urllib "net/url"
+	"testing"
+	"time"
+)
+
+func TestVisitedFilter(t *testing.T) {
+
+	base, _ := urllib.Parse("https://example.com/")
+	links := []link{
+		NewLink("https://example.com/", "Home", &time.Time{}),
+		NewLink("https://example.com/chapter-1", "Chapter 1", &time.Time{}),
+	}
+
+	v := newVisited()
+	v.claim("https://example.com/#top")
+
+	got, _ := v.filter(base, links, false)
+	if len(got) != 1 || got[0].Text != "Chapter 1" {
+		t.Errorf("got %v, wanted only Chapter 1", got)
+	}
+
+	// every link is claimed now
+	got, references := v.filter(base, links, true)
+	if len(got) != 2 || references[0] == false || references[1] == false {
+		t.Errorf("got %v %v, wanted 2 cross references", got, references)
+	}
+
+}
+
+func TestCrossReference(t *testing.T) {
+
+	c := newCrossReference("https://example.com/chapter-1", "Chapter <1>", NewScrapeConfigNoInclude())
+
+	got := c.Content()
+	want := "<p>See <a href=\"https://example.com/chapter-1\">Chapter &lt;1&gt;</a>.</p>"
+
+	if got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+	if c.config.Include == false {
+		t.Errorf("cross reference should always be included")
+	}
+
+}
+
+func TestCanonicalDuplicates(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links := map[string]string{
+			"/":  `<a href="/a">A</a><a href="/b">B</a><a href="/d">D</a>`,
+			"/a": `<a href="/b">B</a>`,
+			"/b": `<a href="/c">C</a><a href="/a">A</a>`,
+		}
+
+		// /c and /d are other URLs of /a
+		head := ""
+		if r.URL.Path == "/c" || r.URL.Path == "/d" {
+			head = `<link rel="canonical" href="/a">`
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>Page %s</title>%s</head><body><p>Content of page %s.</p>%s</body></html>`, r.URL.Path, head, r.URL.Path, links[r.URL.Path])
+	}))
+	defer server.Close()
+
+	config0 := NewScrapeConfigNoInclude()
+	config0.Selector = "a"
+	config1 := NewScrapeConfig()
+	config1.Selector = "a"
+
+	c := NewChapterFromURL(server.URL, "", []*ScrapeConfig{config0, config1, NewScrapeConfig()}, 0, func(index int, name string) {})
+
+	got := []string{}
+	for _, sc := range c.SubChapters() {
+		got = append(got, fmt.Sprintf("%s %d", sc.Name(), len(sc.SubChapters())))
+	}
+	want := []string{"Page /a 0", "Page /b 0"}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+}
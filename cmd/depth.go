@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseDepthValues splits a value such as "3" or "0=3,1=10" into the value of every level and the values of specific levels
+func parseDepthValues(s string) (string, map[int]string, error) {
+	all := ""
+	byDepth := map[int]string{}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+
+		parts := strings.SplitN(part, "=", 2)
+		if len(parts) == 1 {
+			all = part
+			continue
+		}
+
+		depth, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || depth < 0 {
+			return all, byDepth, fmt.Errorf("invalid depth: %s", part)
+		}
+		byDepth[depth] = strings.TrimSpace(parts[1])
+	}
+
+	return all, byDepth, nil
+}
+
+// formatDepthValues is the reverse of parseDepthValues
+func formatDepthValues(all string, byDepth map[int]string) string {
+	depths := []int{}
+	for depth := range byDepth {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+
+	parts := []string{all}
+	for _, depth := range depths {
+		parts = append(parts, fmt.Sprintf("%d=%s", depth, byDepth[depth]))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// depthInt is an int option applied to every level, or to specific levels with the DEPTH=VALUE syntax
+type depthInt struct {
+	all     int
+	byDepth map[int]int
+	allSet  bool
+}
+
+func newDepthInt(value int) *depthInt {
+	return &depthInt{value, map[int]int{}, false}
+}
+
+func (d *depthInt) String() string {
+	byDepth := map[int]string{}
+	for depth, value := range d.byDepth {
+		byDepth[depth] = strconv.Itoa(value)
+	}
+
+	return formatDepthValues(strconv.Itoa(d.all), byDepth)
+}
+
+func (d *depthInt) Set(s string) error {
+	all, byDepth, err := parseDepthValues(s)
+	if err != nil {
+		return err
+	}
+
+	if len(all) > 0 {
+		value, err := strconv.Atoi(all)
+		if err != nil {
+			return fmt.Errorf("invalid value: %s", all)
+		}
+		d.all = value
+		d.allSet = true
+	}
+
+	for depth, s := range byDepth {
+		value, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid value for depth %d: %s", depth, s)
+		}
+		d.byDepth[depth] = value
+	}
+
+	return nil
+}
+
+func (d *depthInt) Type() string {
+	return "[depth=]int"
+}
+
+// isSet tells if the option was specified for depth
+func (d *depthInt) isSet(depth int) bool {
+	_, exists := d.byDepth[depth]
+	return exists || d.allSet
+}
+
+// at returns the value of the option at depth
+func (d *depthInt) at(depth int) int {
+	if value, exists := d.byDepth[depth]; exists {
+		return value
+	}
+
+	return d.all
+}
+
+// depthBool is a bool option applied to every level, or to specific levels with the DEPTH=VALUE syntax
+type depthBool struct {
+	all     bool
+	byDepth map[int]bool
+	allSet  bool
+}
+
+func newDepthBool(value bool) *depthBool {
+	return &depthBool{value, map[int]bool{}, false}
+}
+
+func (d *depthBool) String() string {
+	byDepth := map[int]string{}
+	for depth, value := range d.byDepth {
+		byDepth[depth] = strconv.FormatBool(value)
+	}
+
+	return formatDepthValues(strconv.FormatBool(d.all), byDepth)
+}
+
+func (d *depthBool) Set(s string) error {
+	all, byDepth, err := parseDepthValues(s)
+	if err != nil {
+		return err
+	}
+
+	if len(all) > 0 {
+		value, err := strconv.ParseBool(all)
+		if err != nil {
+			return fmt.Errorf("invalid value: %s", all)
+		}
+		d.all = value
+		d.allSet = true
+	}
+
+	for depth, s := range byDepth {
+		value, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid value for depth %d: %s", depth, s)
+		}
+		d.byDepth[depth] = value
+	}
+
+	return nil
+}
+
+func (d *depthBool) Type() string {
+	return "[depth=]bool"
+}
+
+// isSet tells if the option was specified for depth
+func (d *depthBool) isSet(depth int) bool {
+	_, exists := d.byDepth[depth]
+	return exists || d.allSet
+}
+
+// at returns the value of the option at depth
+func (d *depthBool) at(depth int) bool {
+	if value, exists := d.byDepth[depth]; exists {
+		return value
+	}
+
+	return d.all
+}
+
+// depthString is a string option applied to every level, or to specific levels with the DEPTH=VALUE syntax
+type depthString struct {
+	all     string
+	byDepth map[int]string
+	allSet  bool
+}
+
+func newDepthString(value string) *depthString {
+	return &depthString{value, map[int]string{}, false}
+}
+
+func (d *depthString) String() string {
+	return formatDepthValues(d.all, d.byDepth)
+}
+
+func (d *depthString) Set(s string) error {
+	all, byDepth, err := parseDepthValues(s)
+	if err != nil {
+		return err
+	}
+
+	if len(all) > 0 {
+		d.all = all
+		d.allSet = true
+	}
+
+	for depth, value := range byDepth {
+		d.byDepth[depth] = value
+	}
+
+	return nil
+}
+
+func (d *depthString) Type() string {
+	return "[depth=]string"
+}
+
+// isSet tells if the option was specified for depth
+func (d *depthString) isSet(depth int) bool {
+	_, exists := d.byDepth[depth]
+	return exists || d.allSet
+}
+
+// at returns the value of the option at depth
+func (d *depthString) at(depth int) string {
+	if value, exists := d.byDepth[depth]; exists {
+		return value
+	}
+
+	return d.all
+}
@@ -15,30 +15,32 @@ import (
 type GetOptions struct {
 	// url string
 
-	name   string
-	author string
-	Format string
-	output string
-	stdout bool
-	images bool
-	quiet  bool
-
-	Selector    []string
-	depth       int
-	limit       int
-	offset      int
-	reverse     bool
-	delay       int
-	threads     int
-	include     bool
-	useLinkName bool
+	name       string
+	author     string
+	Format     string
+	output     string
+	stdout     bool
+	images     bool
+	imageWidth int
+	quiet      bool
+
+	inputFile string
+
+	ScrapeOptions
 	separateMarkdown bool
+	crossReference   bool
+	metadata         bool
+	linkStyle        string
+	splitOn          string
+	endnotesAt       string
+	crawl            bool
+	maxPages         int
 }
 
 var getOpts *GetOptions
 
 func init() {
-	getOpts = &GetOptions{}
+	getOpts = &GetOptions{ScrapeOptions: newScrapeOptions()}
 
 	getCmd.Flags().StringVarP(&getOpts.name, "name", "n", "", "book name (default: page title)")
 	getCmd.Flags().StringVarP(&getOpts.author, "author", "a", "", "book author")
@@ -46,19 +48,50 @@ func init() {
 	getCmd.Flags().StringVarP(&getOpts.output, "output", "", "", "file name (default: book name)")
 	getCmd.Flags().BoolVarP(&getOpts.stdout, "stdout", "", false, "print to standard output")
 	getCmd.Flags().BoolVarP(&getOpts.images, "images", "", false, "retrieve images only")
+	getCmd.Flags().IntVarP(&getOpts.imageWidth, "image-width", "", 1200, "preferred width of responsive images, the largest image not wider is used, 0 for the largest")
 	getCmd.Flags().BoolVarP(&getOpts.quiet, "quiet", "q", false, "hide progress bar")
+	getCmd.Flags().StringVarP(&getOpts.inputFile, "input-file", "", "", "file containing URLs to scrape, one per line")
 
 	// common with list command
-	getCmd.Flags().StringSliceVarP(&getOpts.Selector, "selector", "s", []string{}, "table of contents CSS selector")
+	getCmd.Flags().StringSliceVarP(&getOpts.Selector, "selector", "s", []string{}, "table of contents CSS selector, prefix with xpath: to use an XPath expression")
 	getCmd.Flags().IntVarP(&getOpts.depth, "depth", "d", 0, "scraping depth")
-	getCmd.Flags().IntVarP(&getOpts.limit, "limit", "l", -1, "limit number of chapters, use with depth/selector")
-	getCmd.Flags().IntVarP(&getOpts.offset, "offset", "o", 0, "skip first chapters, use with depth/selector")
-	getCmd.Flags().BoolVarP(&getOpts.reverse, "reverse", "r", false, "reverse chapter order")
-	getCmd.Flags().IntVarP(&getOpts.delay, "delay", "", -1, "time in milliseconds to wait before downloading next chapter, use with depth/selector")
-	getCmd.Flags().IntVarP(&getOpts.threads, "threads", "t", -1, "download concurrency, use with depth/selector")
+	getCmd.Flags().VarP(getOpts.limit, "limit", "l", "limit number of chapters, prefix with DEPTH= to set one level only, use with depth/selector")
+	getCmd.Flags().VarP(getOpts.offset, "offset", "o", "skip first chapters, prefix with DEPTH= to set one level only, use with depth/selector")
+	getCmd.Flags().StringArrayVarP(&getOpts.chapters, "chapters", "", []string{}, "select chapters such as 1-5,8,20- or -3 for the last 3, prefix with DEPTH= to select chapters of one level only, use with depth/selector")
+	getCmd.Flags().VarP(getOpts.sort, "sort", "", "sort chapters by [date, title, natural, url] instead of document order, prefix with DEPTH= to set one level only")
+	getCmd.Flags().VarPF(getOpts.reverse, "reverse", "r", "reverse chapter order, prefix with DEPTH= to set one level only").NoOptDefVal = "true"
+	getCmd.Flags().VarP(getOpts.delay, "delay", "", "time in milliseconds to wait before downloading next chapter, prefix with DEPTH= to set one level only, use with depth/selector")
+	getCmd.Flags().VarP(getOpts.threads, "threads", "t", "download concurrency, prefix with DEPTH= to set one level only, use with depth/selector")
 	getCmd.Flags().BoolVarP(&getOpts.include, "include", "i", false, "include URL as first chapter, use with depth/selector")
 	getCmd.Flags().BoolVarP(&getOpts.useLinkName, "use-link-name", "", false, "use link name for chapter title")
 	getCmd.Flags().BoolVarP(&getOpts.separateMarkdown, "separate-md-file", "", false, "save markdown in a separate files")
+	getCmd.Flags().IntVarP(&getOpts.candidate, "candidate", "", 1, "use the Kth most probable table of contents of the first page, see list command candidates option")
+	getCmd.Flags().StringVarP(&getOpts.hrefAttr, "href-attr", "", "href", "attribute containing the link URL, use with depth/selector")
+	getCmd.Flags().StringVarP(&getOpts.titleAttr, "title-attr", "", "", "attribute containing the link name, use with use-link-name")
+	getCmd.Flags().StringVarP(&getOpts.titleSelector, "title-selector", "", "", "CSS selector of the element containing the link name inside the link, use with use-link-name")
+	getCmd.Flags().BoolVarP(&getOpts.crawl, "crawl", "", false, "follow every link of the website breadth first, chapters are nested by URL path")
+	getCmd.Flags().IntVarP(&getOpts.maxPages, "max-pages", "", 100, "maximum number of pages to download, use with crawl")
+	getCmd.Flags().StringVarP(&getOpts.recipeName, "recipe", "", "", "recipe file or name describing the options of each level")
+	getCmd.Flags().BoolVarP(&getOpts.noRecipe, "no-recipe", "", false, "do not use the user recipe matching the URL")
+	getCmd.Flags().StringVarP(&getOpts.jsonLinks, "json-links", "", "", "JSONPath expression of the chapter URLs when the table of contents is a JSON document, such as $.items[*].url")
+	getCmd.Flags().StringVarP(&getOpts.jsonTitles, "json-titles", "", "", "JSONPath expression of the chapter names, use with json-links")
+	getCmd.Flags().StringVarP(&getOpts.jsonDates, "json-dates", "", "", "JSONPath expression of the chapter dates, use with json-links")
+	getCmd.Flags().StringVarP(&getOpts.jsonNext, "json-next", "", "", "JSONPath expression of the next page URL, or cursor with json-cursor-param, use with json-links")
+	getCmd.Flags().StringVarP(&getOpts.jsonCursorParam, "json-cursor-param", "", "", "query parameter receiving the json-next cursor to get the next page")
+	getCmd.Flags().VarP(getOpts.extractor, "extractor", "", "content extractor [readability, selector, body], prefix with DEPTH= to set one level only")
+	getCmd.Flags().StringArrayVarP(&getOpts.contentSelectors, "content-selector", "", []string{}, "CSS selector of the content, instead of the readability guess, prefix with DEPTH= to set one level only")
+	getCmd.Flags().StringArrayVarP(&getOpts.remove, "remove", "", []string{}, "CSS selector of elements removed from the page before extracting the content, prefix with DEPTH= to set one level only")
+	getCmd.Flags().StringArrayVarP(&getOpts.removeAfter, "remove-after", "", []string{}, "CSS selector of elements removed from the extracted content, prefix with DEPTH= to set one level only")
+	getCmd.Flags().StringVarP(&getOpts.renderCmd, "render-cmd", "", "", "command printing the HTML of {url}, such as 'chromium --headless --dump-dom {url}', to scrape websites rendered with JavaScript")
+	getCmd.Flags().IntVarP(&getOpts.renderTimeout, "render-timeout", "", 60, "time in seconds before the render command is stopped")
+	getCmd.Flags().IntVarP(&getOpts.renderThreads, "render-threads", "", 2, "maximum number of render commands running at the same time")
+	getCmd.Flags().StringVarP(&getOpts.renderCache, "render-cache", "", "", "directory where rendered pages are saved to be reused")
+	getCmd.Flags().StringVarP(&getOpts.scriptName, "script", "", "", "Starlark file defining links, title and transform hooks")
+	getCmd.Flags().StringVarP(&getOpts.splitOn, "split-on", "", "", "split chapters into sections at the headings matching this CSS selector, such as h2")
+	getCmd.Flags().StringVarP(&getOpts.linkStyle, "link-style", "", "inline", "display of links to web pages [inline, endnotes, strip]")
+	getCmd.Flags().StringVarP(&getOpts.endnotesAt, "endnotes-at", "", "chapter", "list endnotes at the end of each [chapter] or of the [book], use with link-style=endnotes")
+	getCmd.Flags().BoolVarP(&getOpts.metadata, "metadata", "", false, "print author, site name, publication date and source URL under chapter titles")
+	getCmd.Flags().BoolVarP(&getOpts.crossReference, "cross-ref", "", false, "link to chapters already in the book instead of skipping them, use with depth/selector")
 
 	rootCmd.AddCommand(getCmd)
 }
@@ -68,7 +101,7 @@ var getCmd = &cobra.Command{
 	Short:   "Scrape URL content",
 	Example: "papeer get https://www.eff.org/cyberspace-independence",
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
+		if len(args) < 1 && len(getOpts.inputFile) == 0 {
 			return errors.New("requires an URL argument")
 		}
 
@@ -84,6 +117,26 @@ var getCmd = &cobra.Command{
 			return fmt.Errorf("invalid format specified: %s", getOpts.Format)
 		}
 
+		linkStyleEnum := map[string]bool{}
+		for _, style := range book.LinkStyles {
+			linkStyleEnum[style] = true
+		}
+		if linkStyleEnum[getOpts.linkStyle] != true {
+			return fmt.Errorf("invalid link style specified: %s", getOpts.linkStyle)
+		}
+
+		if getOpts.endnotesAt != "chapter" && getOpts.endnotesAt != "book" {
+			return fmt.Errorf("invalid endnotes-at specified: %s", getOpts.endnotesAt)
+		}
+
+		if cmd.Flags().Changed("endnotes-at") && getOpts.linkStyle != "endnotes" {
+			return errors.New("cannot use endnotes-at option if link-style is not endnotes")
+		}
+
+		if getOpts.separateMarkdown && getOpts.endnotesAt == "book" {
+			return errors.New("cannot use endnotes-at=book with separate-md-file, use endnotes-at=chapter")
+		}
+
 		// add .mobi to filename if not specified
 		if getOpts.Format == "mobi" {
 			if len(getOpts.output) > 0 && strings.HasSuffix(getOpts.output, ".mobi") == false {
@@ -91,15 +144,41 @@ var getCmd = &cobra.Command{
 			}
 		}
 
-		// increase depth to match limit
-		if cmd.Flags().Changed("limit") && getOpts.depth == 0 {
-			getOpts.depth = 1
+		if getOpts.crawl && (getOpts.depth > 0 || len(getOpts.Selector) > 0) {
+			return errors.New("cannot use crawl option with depth/selector")
+		}
+
+		if cmd.Flags().Changed("max-pages") && getOpts.crawl == false {
+			return errors.New("cannot use max-pages option if crawl is not specified")
+		}
+
+		if getOpts.maxPages < 1 {
+			return errors.New("max-pages option must be greater than 0")
 		}
 
-		// fill selector array with empty selectors to match depth
-		getOpts.Selector = append(getOpts.Selector, "")
-		for len(getOpts.Selector) < getOpts.depth+1 {
-			getOpts.Selector = append(getOpts.Selector, "")
+		if getOpts.crawl && len(getOpts.recipeName) > 0 {
+			return errors.New("cannot use crawl option with recipe")
+		}
+
+		if getOpts.crawl == false {
+			url := ""
+			if len(args) > 0 {
+				url = args[0]
+			}
+
+			if err := getOpts.loadRecipe(cmd, url); err != nil {
+				return err
+			}
+		}
+
+		if err := getOpts.loadScript(); err != nil {
+			return err
+		}
+
+		getOpts.fillSelectors(cmd)
+
+		if err := getOpts.checkExtractors(); err != nil {
+			return err
 		}
 
 		if cmd.Flags().Changed("include") && getOpts.depth == 0 && len(getOpts.Selector) == 0 {
@@ -126,8 +205,36 @@ var getCmd = &cobra.Command{
 			return errors.New("cannot use use-link-name option if depth/selector is not specified")
 		}
 
-		if cmd.Flags().Changed("delay") && cmd.Flags().Changed("threads") {
-			return errors.New("cannot use delay and threads options at the same time")
+		if cmd.Flags().Changed("cross-ref") && getOpts.depth == 0 && len(getOpts.Selector) == 0 {
+			return errors.New("cannot use cross-ref option if depth/selector is not specified")
+		}
+
+		if _, _, err := getOpts.chaptersByDepth(); err != nil {
+			return err
+		}
+
+		if err := getOpts.checkSort(); err != nil {
+			return err
+		}
+
+		if err := getOpts.checkJSON(); err != nil {
+			return err
+		}
+
+		if err := getOpts.loadRenderer(cmd); err != nil {
+			return err
+		}
+
+		if getOpts.imageWidth < 0 {
+			return errors.New("image-width option must be positive")
+		}
+
+		if getOpts.candidate < 1 {
+			return errors.New("candidate option must be greater than 0")
+		}
+
+		if err := getOpts.checkDelayThreads(); err != nil {
+			return err
 		}
 
 		// TODO: is it better to add check so if they used separate-md-file without use-link-name it returns an error
@@ -140,42 +247,60 @@ var getCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 
 		// generate config for each level
-		configs := make([]*book.ScrapeConfig, len(getOpts.Selector))
-		for index, s := range getOpts.Selector {
-			config := book.NewScrapeConfig()
-			config.Selector = s
+		configs := getOpts.Configs(cmd)
+		for _, config := range configs {
 			config.Quiet = getOpts.quiet
-			config.Limit = getOpts.limit
-			config.Offset = getOpts.offset
-			config.Reverse = getOpts.reverse
-			config.Delay = getOpts.delay
-			config.Threads = getOpts.threads
-			config.ImagesOnly = getOpts.images
-			config.Include = getOpts.include
-			config.UseLinkName = getOpts.useLinkName
+			config.ImagesOnly = config.ImagesOnly || getOpts.images
+			config.ImageWidth = getOpts.imageWidth
 			config.SeparateMarkdown = getOpts.separateMarkdown
-
-			// do not use link name for root level as there is not parent link
-			if index == 0 {
-				config.UseLinkName = false
-			}
-
-			// always include last level by default
-			if index == len(getOpts.Selector)-1 {
-				config.Include = true
-			}
-
-			configs[index] = config
+			config.ShowMetadata = getOpts.metadata
+			config.CrossReference = config.CrossReference || getOpts.crossReference
 		}
 
 		// dummy root chapter to contain all subchapters
 		c := book.NewEmptyChapter()
 
-		for _, u := range args {
+		urls := args
+		if len(getOpts.inputFile) > 0 {
+			urls = append(urls, readURLs(getOpts.inputFile)...)
+		}
+
+		for _, u := range urls {
+			if getOpts.crawl {
+				c.AddSubChapter(book.NewChapterFromCrawl(u, configs[0], getOpts.maxPages))
+				continue
+			}
+
 			newChapter := book.NewChapterFromURL(u, "", configs, 0, func(index int, name string) {})
 			c.AddSubChapter(newChapter)
 		} //["a", "b", "c"]
 		c.SetName(c.SubChapters()[0].Name())
+
+		// book metadata, command line options take precedence over the recipe
+		name, author := getOpts.name, getOpts.author
+		if getOpts.recipe != nil {
+			if len(name) == 0 {
+				name = getOpts.recipe.Metadata.Title
+			}
+			if len(author) == 0 {
+				author = getOpts.recipe.Metadata.Author
+			}
+		}
+		if len(name) > 0 {
+			c.SetName(name)
+		}
+		if len(author) > 0 {
+			c.SetAuthor(author)
+		}
+
+		// long pages are divided at their headings
+		if len(getOpts.splitOn) > 0 {
+			c = book.SplitChapters(c, getOpts.splitOn)
+		}
+
+		// links between chapters point inside the book
+		c = book.LinkChapters(c, getOpts.Format)
+		c = book.StyleLinks(c, getOpts.linkStyle, getOpts.endnotesAt == "book", getOpts.Format)
 		// TODO Locate the part where the parsed data is aggregated and saved to a single MD file.
 		if getOpts.Format == "md" {
 			if getOpts.separateMarkdown {
@@ -225,9 +350,12 @@ var getCmd = &cobra.Command{
 				log.Fatal(err)
 			}
 
+			chapters := book.NewChapterInfo(c).Chapters
+
 			book := make(map[string]interface{})
 			book["name"] = c.Name()
 			book["content"] = string(bytesRead)
+			book["chapters"] = chapters
 
 			bookJson, err := json.Marshal(book)
 			if err != nil {
@@ -283,3 +411,23 @@ var getCmd = &cobra.Command{
 		}
 	},
 }
+
+// readURLs returns the URLs listed in filename, ignoring empty lines and lines starting with #
+func readURLs(filename string) []string {
+	bytesRead, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	urls := []string{}
+	for _, line := range strings.Split(string(bytesRead), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		urls = append(urls, line)
+	}
+
+	return urls
+}
@@ -20,36 +20,52 @@ type ListOptions struct {
 
 	output string
 
-	Selector    []string
-	depth       int
-	limit       int
-	offset      int
-	reverse     bool
-	delay       int
-	threads     int
-	include     bool
-	useLinkName bool
+	ScrapeOptions
 	separateMarkdown bool
+	candidates       int
+	maxFetch         int
+	check            bool
 }
 
 var listOpts *ListOptions
 
 func init() {
-	listOpts = &ListOptions{}
+	listOpts = &ListOptions{ScrapeOptions: newScrapeOptions()}
 
-	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "table", "file format [table, json]")
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "table", "file format [table, json, csv, md, opml, urls]")
 
 	// common with get command
-	listCmd.Flags().StringSliceVarP(&listOpts.Selector, "selector", "s", []string{}, "table of contents CSS selector")
+	listCmd.Flags().StringSliceVarP(&listOpts.Selector, "selector", "s", []string{}, "table of contents CSS selector, prefix with xpath: to use an XPath expression")
 	listCmd.Flags().IntVarP(&listOpts.depth, "depth", "d", 0, "scraping depth")
-	listCmd.Flags().IntVarP(&listOpts.limit, "limit", "l", -1, "limit number of chapters, use with depth/selector")
-	listCmd.Flags().IntVarP(&listOpts.offset, "offset", "", 0, "skip first chapters, use with depth/selector")
-	listCmd.Flags().BoolVarP(&listOpts.reverse, "reverse", "r", false, "reverse chapter order")
-	listCmd.Flags().IntVarP(&listOpts.delay, "delay", "", -1, "time in milliseconds to wait before downloading next chapter, use with depth/selector")
-	listCmd.Flags().IntVarP(&listOpts.threads, "threads", "t", -1, "download concurrency, use with depth/selector")
+	listCmd.Flags().VarP(listOpts.limit, "limit", "l", "limit number of chapters, prefix with DEPTH= to set one level only, use with depth/selector")
+	listCmd.Flags().VarP(listOpts.offset, "offset", "", "skip first chapters, prefix with DEPTH= to set one level only, use with depth/selector")
+	listCmd.Flags().StringArrayVarP(&listOpts.chapters, "chapters", "", []string{}, "select chapters such as 1-5,8,20- or -3 for the last 3, prefix with DEPTH= to select chapters of one level only, use with depth/selector")
+	listCmd.Flags().VarP(listOpts.sort, "sort", "", "sort chapters by [date, title, natural, url] instead of document order, prefix with DEPTH= to set one level only")
+	listCmd.Flags().VarPF(listOpts.reverse, "reverse", "r", "reverse chapter order, prefix with DEPTH= to set one level only").NoOptDefVal = "true"
+	listCmd.Flags().VarP(listOpts.delay, "delay", "", "time in milliseconds to wait before downloading next chapter, prefix with DEPTH= to set one level only, use with depth/selector")
+	listCmd.Flags().VarP(listOpts.threads, "threads", "t", "download concurrency, prefix with DEPTH= to set one level only, use with depth/selector")
 	listCmd.Flags().BoolVarP(&listOpts.include, "include", "i", false, "include URL as first chapter, use with depth/selector")
 	listCmd.Flags().BoolVarP(&listOpts.useLinkName, "use-link-name", "", false, "use link name for chapter title")
 	listCmd.Flags().BoolVarP(&listOpts.separateMarkdown, "separate-md-file", "", false, "save markdown in a separate files for each chapter")
+	listCmd.Flags().IntVarP(&listOpts.candidates, "candidates", "", 0, "print the N most probable tables of contents instead of links")
+	listCmd.Flags().IntVarP(&listOpts.candidate, "candidate", "", 1, "use the Kth most probable table of contents of the first page, see candidates option")
+	listCmd.Flags().StringVarP(&listOpts.hrefAttr, "href-attr", "", "href", "attribute containing the link URL")
+	listCmd.Flags().StringVarP(&listOpts.titleAttr, "title-attr", "", "", "attribute containing the link name (default: link text)")
+	listCmd.Flags().StringVarP(&listOpts.titleSelector, "title-selector", "", "", "CSS selector of the element containing the link name, inside the link")
+	listCmd.Flags().StringVarP(&listOpts.recipeName, "recipe", "", "", "recipe file or name describing the options of each level")
+	listCmd.Flags().BoolVarP(&listOpts.noRecipe, "no-recipe", "", false, "do not use the user recipe matching the URL")
+	listCmd.Flags().StringVarP(&listOpts.jsonLinks, "json-links", "", "", "JSONPath expression of the chapter URLs when the table of contents is a JSON document, such as $.items[*].url")
+	listCmd.Flags().StringVarP(&listOpts.jsonTitles, "json-titles", "", "", "JSONPath expression of the chapter names, use with json-links")
+	listCmd.Flags().StringVarP(&listOpts.jsonDates, "json-dates", "", "", "JSONPath expression of the chapter dates, use with json-links")
+	listCmd.Flags().StringVarP(&listOpts.jsonNext, "json-next", "", "", "JSONPath expression of the next page URL, or cursor with json-cursor-param, use with json-links")
+	listCmd.Flags().StringVarP(&listOpts.jsonCursorParam, "json-cursor-param", "", "", "query parameter receiving the json-next cursor to get the next page")
+	listCmd.Flags().StringVarP(&listOpts.renderCmd, "render-cmd", "", "", "command printing the HTML of {url}, such as 'chromium --headless --dump-dom {url}', to scrape websites rendered with JavaScript")
+	listCmd.Flags().IntVarP(&listOpts.renderTimeout, "render-timeout", "", 60, "time in seconds before the render command is stopped")
+	listCmd.Flags().IntVarP(&listOpts.renderThreads, "render-threads", "", 2, "maximum number of render commands running at the same time")
+	listCmd.Flags().StringVarP(&listOpts.renderCache, "render-cache", "", "", "directory where rendered pages are saved to be reused")
+	listCmd.Flags().StringVarP(&listOpts.scriptName, "script", "", "", "Starlark file defining a links hook")
+	listCmd.Flags().BoolVarP(&listOpts.check, "check", "", false, "request every link and print its status code, redirection, content type and size, respects delay and threads")
+	listCmd.Flags().IntVarP(&listOpts.maxFetch, "max-fetch", "", -1, "maximum number of tables of contents to retrieve, use with depth/selector")
 
 	rootCmd.AddCommand(listCmd)
 }
@@ -68,35 +84,85 @@ var listCmd = &cobra.Command{
 		outputEnum := map[string]bool{
 			"table": true,
 			"json":  true,
+			"csv":   true,
+			"md":    true,
+			"opml":  true,
+			"urls":  true,
 		}
 		if outputEnum[listOpts.output] != true {
 			return fmt.Errorf("invalid output specified: %s", listOpts.output)
 		}
 
+		if listOpts.check && listOpts.output != "table" && listOpts.output != "json" && listOpts.output != "csv" {
+			return fmt.Errorf("cannot use check option with %s output", listOpts.output)
+		}
+
+		if listOpts.candidates < 0 {
+			return errors.New("candidates option must be positive")
+		}
+
+		if listOpts.candidates > 0 && listOpts.output != "table" && listOpts.output != "json" {
+			return fmt.Errorf("cannot use candidates option with %s output", listOpts.output)
+		}
+
+		if _, _, err := listOpts.chaptersByDepth(); err != nil {
+			return err
+		}
+
+		if err := listOpts.checkSort(); err != nil {
+			return err
+		}
+
+		if err := listOpts.checkJSON(); err != nil {
+			return err
+		}
+
+		if err := listOpts.loadRenderer(cmd); err != nil {
+			return err
+		}
+
+		if listOpts.candidate < 1 {
+			return errors.New("candidate option must be greater than 0")
+		}
+
+		if listOpts.maxFetch < -1 || listOpts.maxFetch == 0 {
+			return errors.New("max-fetch option must be greater than 0")
+		}
+
+		if err := listOpts.loadRecipe(cmd, args[0]); err != nil {
+			return err
+		}
+
+		if err := listOpts.loadScript(); err != nil {
+			return err
+		}
+
+		listOpts.fillSelectors(cmd)
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(listOpts.Selector) == 0 {
-			listOpts.Selector = []string{""}
-		}
-
 		base, err := urllib.Parse(args[0])
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		links, path, home, err := book.GetLinks(base, listOpts.Selector[0], listOpts.limit, listOpts.offset, listOpts.reverse, listOpts.include)
+		// same configs as get command
+		configs := listOpts.Configs(cmd)
+
+		if listOpts.candidates > 0 {
+			printCandidates(base, configs[0], listOpts.candidates)
+			return
+		}
+
+		links, pathFormatted, home, err := book.GetLinkTree(base, configs, listOpts.include, listOpts.maxFetch)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		// format selector path
-		pathArray := strings.Split(path, "<")
-		// reverse path
-		for i, j := 0, len(pathArray)-1; i < j; i, j = i+1, j-1 {
-			pathArray[i], pathArray[j] = pathArray[j], pathArray[i]
+		if listOpts.check {
+			book.CheckLinkTree(links, configs)
 		}
-		pathFormatted := strings.Join(pathArray, ">")
 
 		switch listOpts.output {
 
@@ -108,17 +174,18 @@ var listCmd = &cobra.Command{
 			t.Style().Options.SeparateColumns = false
 			t.Style().Options.SeparateHeader = false
 
-			t.SetTitle(home.Name())
-			t.AppendHeader(table.Row{"#", "Name", fmt.Sprintf("Url [%s]", pathFormatted)})
-
-			for index, link := range links {
-				u, err := base.Parse(link.Href)
-				if err != nil {
-					log.Fatal(err)
-				}
+			dates := hasDates(links)
 
-				t.AppendRow([]interface{}{index + 1, link.Text, u.String()})
+			t.SetTitle(home.Name())
+			header := table.Row{"#", "Name", fmt.Sprintf("Url [%s]", pathFormatted)}
+			if dates {
+				header = append(header, "Date")
+			}
+			if listOpts.check {
+				header = append(header, "Status", "Redirect", "Type", "Size", "Check")
 			}
+			t.AppendHeader(header)
+			appendLinkRows(t, links, "", 0, dates, listOpts.check)
 
 			t.Render()
 
@@ -126,13 +193,14 @@ var listCmd = &cobra.Command{
 		case "json":
 			book := make(map[string]interface{})
 			book["url"] = base.String()
-			if pathFormatted == "RSS" {
-				book["type"] = "RSS"
+			if pathFormatted == "RSS" || pathFormatted == "JSON" {
+				book["type"] = pathFormatted
 			} else {
 				book["type"] = "HTML"
 			}
 			book["path"] = pathFormatted
 			book["name"] = home.Name()
+			book["metadata"] = home.Metadata()
 			book["chapters"] = links
 
 			bookJson, err := json.Marshal(book)
@@ -141,7 +209,113 @@ var listCmd = &cobra.Command{
 			}
 
 			fmt.Println(string(bookJson))
+
+		case "csv":
+			printCSV(links, listOpts.check)
+
+		case "md":
+			printMarkdown(home.Name(), links)
+
+		case "opml":
+			printOPML(home.Name(), links)
+
+		case "urls":
+			printURLs(links)
+		}
+
+		if listOpts.check {
+			if problems := countProblems(links); problems > 0 {
+				fmt.Fprintf(os.Stderr, "%d links cannot be scraped\n", problems)
+			}
 		}
 
 	},
 }
+
+// appendLinkRows adds a row for each link of the tree, subchapters are numbered after their parent and indented
+func appendLinkRows(t table.Writer, nodes []book.LinkNode, prefix string, depth int, dates, check bool) {
+	for index, node := range nodes {
+		number := fmt.Sprintf("%s%d", prefix, index+1)
+		name := strings.Repeat("  ", depth) + node.Text
+
+		row := table.Row{number, name, node.Href}
+		if dates {
+			row = append(row, formatDate(node, "2006-01-02"))
+		}
+		if check {
+			for _, column := range checkColumns(node, true) {
+				row = append(row, column)
+			}
+		}
+		t.AppendRow(row)
+
+		appendLinkRows(t, node.Chapters, number+".", depth+1, dates, check)
+	}
+}
+
+// number of links displayed for each candidate
+const candidateSampleSize = 3
+
+func printCandidates(base *urllib.URL, config *book.ScrapeConfig, n int) {
+	candidates, err := book.GetCandidates(base, config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	switch listOpts.output {
+
+	// render as table
+	case "table":
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.Style().Options.DrawBorder = false
+		t.Style().Options.SeparateColumns = false
+		t.Style().Options.SeparateHeader = false
+
+		t.SetTitle(base.String())
+		t.AppendHeader(table.Row{"#", "Selector", "Links", "Score", "Sample"})
+
+		for index, c := range candidates {
+			sample := []string{}
+			for i, link := range c.Links {
+				if i == candidateSampleSize {
+					break
+				}
+				sample = append(sample, fmt.Sprintf("%s (%s)", link.Text, link.Href))
+			}
+
+			t.AppendRow([]interface{}{index + 1, c.Selector, len(c.Links), c.Score, strings.Join(sample, "\n")})
+		}
+
+		t.Render()
+
+	// render as json
+	case "json":
+		results := []map[string]interface{}{}
+		for _, c := range candidates {
+			sample := c.Links
+			if len(sample) > candidateSampleSize {
+				sample = sample[:candidateSampleSize]
+			}
+
+			result := make(map[string]interface{})
+			result["selector"] = c.Selector
+			result["count"] = len(c.Links)
+			result["score"] = c.Score
+			result["sample"] = sample
+
+			results = append(results, result)
+		}
+
+		resultsJson, err := json.Marshal(results)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(string(resultsJson))
+	}
+}
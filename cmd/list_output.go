@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lapwat/papeer/book"
+)
+
+// hasDates tells if some links of the tree come from a feed and have a publication date
+func hasDates(nodes []book.LinkNode) bool {
+	for _, node := range nodes {
+		if node.Date != nil && node.Date.IsZero() == false {
+			return true
+		}
+		if hasDates(node.Chapters) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatDate returns the date of the link, or an empty string if it has none
+func formatDate(node book.LinkNode, layout string) string {
+	if node.Date == nil || node.Date.IsZero() {
+		return ""
+	}
+
+	return node.Date.Format(layout)
+}
+
+// checkColumns returns the status code, final URL if redirected, content type, size and problem of a checked link
+// the size is in bytes unless human is set
+func checkColumns(node book.LinkNode, human bool) []string {
+	s := node.Status
+	if s == nil {
+		return []string{"", "", "", "", ""}
+	}
+
+	code := ""
+	if s.Code > 0 {
+		code = fmt.Sprint(s.Code)
+	}
+
+	redirect := ""
+	if s.FinalURL != node.Href {
+		redirect = s.FinalURL
+	}
+
+	problem := s.Problem()
+	if len(problem) == 0 {
+		problem = "ok"
+	}
+
+	size := ""
+	if human {
+		size = formatSize(s.Size)
+	} else if s.Size >= 0 {
+		size = fmt.Sprint(s.Size)
+	}
+
+	return []string{code, redirect, s.ContentType, size, problem}
+}
+
+// formatSize returns a human readable size, or ? if unknown
+func formatSize(size int64) string {
+	if size < 0 {
+		return "?"
+	}
+
+	units := []string{"B", "kB", "MB", "GB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// countProblems returns the number of checked links that cannot be scraped
+func countProblems(nodes []book.LinkNode) int {
+	count := 0
+	for _, node := range nodes {
+		if node.Status != nil && len(node.Status.Problem()) > 0 {
+			count++
+		}
+		count += countProblems(node.Chapters)
+	}
+
+	return count
+}
+
+// printCSV prints one row per link, with its number, depth, name, URL, date for feeds and status if checked
+func printCSV(nodes []book.LinkNode, check bool) {
+	dates := hasDates(nodes)
+
+	w := csv.NewWriter(os.Stdout)
+
+	header := []string{"number", "depth", "name", "url"}
+	if dates {
+		header = append(header, "date")
+	}
+	if check {
+		header = append(header, "status", "redirect", "content_type", "size", "check")
+	}
+	w.Write(header)
+
+	var write func(nodes []book.LinkNode, prefix string, depth int)
+	write = func(nodes []book.LinkNode, prefix string, depth int) {
+		for index, node := range nodes {
+			number := fmt.Sprintf("%s%d", prefix, index+1)
+
+			row := []string{number, fmt.Sprint(depth), node.Text, node.Href}
+			if dates {
+				row = append(row, formatDate(node, time.RFC3339))
+			}
+			if check {
+				row = append(row, checkColumns(node, false)...)
+			}
+			w.Write(row)
+
+			write(node.Chapters, number+".", depth+1)
+		}
+	}
+	write(nodes, "", 0)
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// printMarkdown prints the table of contents as a nested list of links
+func printMarkdown(name string, nodes []book.LinkNode) {
+	fmt.Printf("# %s\n\n", name)
+
+	escaper := strings.NewReplacer("[", "\\[", "]", "\\]")
+
+	var write func(nodes []book.LinkNode, depth int)
+	write = func(nodes []book.LinkNode, depth int) {
+		for _, node := range nodes {
+			line := fmt.Sprintf("%s- [%s](%s)", strings.Repeat("  ", depth), escaper.Replace(node.Text), node.Href)
+			if date := formatDate(node, "2006-01-02"); len(date) > 0 {
+				line += fmt.Sprintf(" (%s)", date)
+			}
+			fmt.Println(line)
+
+			write(node.Chapters, depth+1)
+		}
+	}
+	write(nodes, 0)
+}
+
+type opml struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated"`
+	Outline []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text    string        `xml:"text,attr"`
+	Type    string        `xml:"type,attr"`
+	Url     string        `xml:"url,attr"`
+	Created string        `xml:"created,attr,omitempty"`
+	Outline []opmlOutline `xml:"outline"`
+}
+
+// printOPML prints the table of contents as an OPML outline, readable by feed readers and outliners
+func printOPML(name string, nodes []book.LinkNode) {
+	var outlines func(nodes []book.LinkNode) []opmlOutline
+	outlines = func(nodes []book.LinkNode) []opmlOutline {
+		result := []opmlOutline{}
+		for _, node := range nodes {
+			result = append(result, opmlOutline{node.Text, "link", node.Href, formatDate(node, time.RFC1123Z), outlines(node.Chapters)})
+		}
+		return result
+	}
+
+	document := opml{Version: "2.0", Title: name, Created: time.Now().Format(time.RFC1123Z), Outline: outlines(nodes)}
+
+	output, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(xml.Header + string(output))
+}
+
+// printURLs prints one URL per line, the output can be used with the input-file option of get command
+func printURLs(nodes []book.LinkNode) {
+	for _, node := range nodes {
+		fmt.Println(node.Href)
+		printURLs(node.Chapters)
+	}
+}
@@ -0,0 +1,404 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lapwat/papeer/book"
+	"github.com/spf13/cobra"
+)
+
+// ScrapeOptions are the table of contents options common to get and list commands
+type ScrapeOptions struct {
+	Selector         []string
+	depth            int
+	limit            *depthInt
+	offset           *depthInt
+	reverse          *depthBool
+	delay            *depthInt
+	threads          *depthInt
+	sort             *depthString
+	include          bool
+	useLinkName      bool
+	candidate        int
+	hrefAttr         string
+	titleAttr        string
+	titleSelector    string
+	chapters         []string
+	extractor        *depthString
+	contentSelectors []string
+	remove           []string
+	removeAfter      []string
+	recipeName       string
+	noRecipe         bool
+	recipe           *book.Recipe
+	scriptName       string
+	script           *book.Script
+
+	// external command rendering pages
+	renderCmd     string
+	renderTimeout int
+	renderThreads int
+	renderCache   string
+	renderer      *book.Renderer
+
+	// JSON API table of contents, first level only
+	jsonLinks       string
+	jsonTitles      string
+	jsonDates       string
+	jsonNext        string
+	jsonCursorParam string
+}
+
+func newScrapeOptions() ScrapeOptions {
+	return ScrapeOptions{
+		limit:     newDepthInt(-1),
+		offset:    newDepthInt(0),
+		reverse:   newDepthBool(false),
+		delay:     newDepthInt(-1),
+		threads:   newDepthInt(-1),
+		sort:      newDepthString(""),
+		extractor: newDepthString(""),
+	}
+}
+
+// checkDelayThreads returns an error if delay and threads options are both set for a level
+func (o *ScrapeOptions) checkDelayThreads() error {
+	for depth := range o.Selector {
+		if o.delay.at(depth) >= 0 && o.threads.at(depth) != -1 {
+			return fmt.Errorf("cannot use delay and threads options at the same time, at depth %d", depth)
+		}
+	}
+
+	return nil
+}
+
+// checkSort returns an error if a sort order is invalid
+func (o *ScrapeOptions) checkSort() error {
+	if err := book.CheckSort(o.sort.all); err != nil {
+		return err
+	}
+
+	for _, order := range o.sort.byDepth {
+		if err := book.CheckSort(order); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkJSON returns an error if a JSONPath expression is invalid or used without json-links
+func (o *ScrapeOptions) checkJSON() error {
+	for _, expression := range []string{o.jsonLinks, o.jsonTitles, o.jsonDates, o.jsonNext} {
+		if err := book.CheckJSONPath(expression); err != nil {
+			return err
+		}
+	}
+
+	if len(o.jsonLinks) == 0 && (len(o.jsonTitles) > 0 || len(o.jsonDates) > 0 || len(o.jsonNext) > 0) {
+		return errors.New("cannot use json-titles, json-dates and json-next options if json-links is not specified")
+	}
+
+	if len(o.jsonNext) == 0 && len(o.jsonCursorParam) > 0 {
+		return errors.New("cannot use json-cursor-param option if json-next is not specified")
+	}
+
+	return nil
+}
+
+// loadRenderer creates the renderer if a render command is specified
+func (o *ScrapeOptions) loadRenderer(cmd *cobra.Command) error {
+	if len(o.renderCmd) == 0 {
+		for _, name := range []string{"render-timeout", "render-threads", "render-cache"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("cannot use %s option if render-cmd is not specified", name)
+			}
+		}
+
+		return nil
+	}
+
+	if o.renderTimeout < 1 {
+		return errors.New("render-timeout option must be greater than 0")
+	}
+
+	r, err := book.NewRenderer(o.renderCmd, time.Duration(o.renderTimeout)*time.Second, o.renderThreads, o.renderCache)
+	if err != nil {
+		return err
+	}
+	o.renderer = r
+
+	return nil
+}
+
+// extractorAt returns the extractor of a level, the boolean is false if no option is set for this level
+// content selectors imply the selector extractor, prefix them with DEPTH= to set one level only
+func (o *ScrapeOptions) extractorAt(depth int) (book.Extractor, bool, error) {
+	selectors := valuesAt(o.contentSelectors, depth)
+
+	name := o.extractor.at(depth)
+	if o.extractor.isSet(depth) == false && len(selectors) == 0 {
+		return nil, false, nil
+	}
+	if len(name) == 0 {
+		name = "selector"
+	}
+
+	e, err := book.NewExtractor(name, selectors)
+	if err != nil {
+		return nil, true, fmt.Errorf("depth %d: %v", depth, err)
+	}
+
+	return e, true, nil
+}
+
+// valuesAt returns the values of a repeatable selector option for a level.
+// Values prefixed with DEPTH= replace the unprefixed ones for this level.
+func valuesAt(values []string, depth int) []string {
+	all := []string{}
+	byDepth := []string{}
+	for _, value := range values {
+		// CSS selectors may contain =, only a number is a depth
+		if parts := strings.SplitN(value, "=", 2); len(parts) == 2 {
+			if d, err := strconv.Atoi(strings.TrimSpace(parts[0])); err == nil {
+				if d == depth {
+					byDepth = append(byDepth, parts[1])
+				}
+				continue
+			}
+		}
+
+		all = append(all, value)
+	}
+
+	if len(byDepth) > 0 {
+		return byDepth
+	}
+	return all
+}
+
+// checkExtractors returns an error if the extractor options of a level are invalid, use after fillSelectors
+func (o *ScrapeOptions) checkExtractors() error {
+	for depth := range o.Selector {
+		if _, _, err := o.extractorAt(depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadRecipe loads the recipe option, or the user recipe matching url when no table of contents option is set
+func (o *ScrapeOptions) loadRecipe(cmd *cobra.Command, url string) error {
+	if len(o.recipeName) > 0 {
+		if cmd.Flags().Changed("depth") {
+			return errors.New("cannot use depth option with recipe, levels are defined by the recipe")
+		}
+
+		r, err := book.FindRecipe(o.recipeName)
+		if err != nil {
+			return err
+		}
+		o.recipe = r
+	} else if o.noRecipe == false && len(url) > 0 && cmd.Flags().Changed("depth") == false && cmd.Flags().Changed("selector") == false {
+		o.recipe = book.MatchRecipe(url)
+	}
+
+	if o.recipe != nil && len(o.Selector) > len(o.recipe.Levels) {
+		return fmt.Errorf("recipe %s has only %d levels", o.recipe.Name, len(o.recipe.Levels))
+	}
+
+	return nil
+}
+
+// loadScript loads the script option, or the script of the recipe
+func (o *ScrapeOptions) loadScript() error {
+	filename := o.scriptName
+	if len(filename) == 0 && o.recipe != nil {
+		filename = o.recipe.Script
+	}
+
+	if len(filename) == 0 {
+		return nil
+	}
+
+	s, err := book.LoadScript(filename)
+	if err != nil {
+		return err
+	}
+	o.script = s
+
+	return nil
+}
+
+// fillSelectors adds an empty selector for each level of depth, plus one for the chapters content
+func (o *ScrapeOptions) fillSelectors(cmd *cobra.Command) {
+	// one selector per recipe level, selectors on the command line override the first levels
+	if o.recipe != nil {
+		for len(o.Selector) < len(o.recipe.Levels) {
+			o.Selector = append(o.Selector, "")
+		}
+		return
+	}
+
+	// increase depth to match limit, chapters, sort and json-links
+	if (cmd.Flags().Changed("limit") || cmd.Flags().Changed("chapters") || cmd.Flags().Changed("sort") || cmd.Flags().Changed("json-links")) && o.depth == 0 {
+		o.depth = 1
+	}
+
+	// fill selector array with empty selectors to match depth
+	o.Selector = append(o.Selector, "")
+	for len(o.Selector) < o.depth+1 {
+		o.Selector = append(o.Selector, "")
+	}
+}
+
+// chaptersByDepth returns the chapter selection of every level and the ones set for a specific level with the DEPTH=RANGE syntax
+func (o *ScrapeOptions) chaptersByDepth() (string, map[int]string, error) {
+	all := ""
+	byDepth := map[int]string{}
+
+	for _, value := range o.chapters {
+		selection := value
+		depth := -1
+
+		if parts := strings.SplitN(value, "=", 2); len(parts) == 2 {
+			d, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+			if err != nil || d < 0 {
+				return all, byDepth, fmt.Errorf("invalid depth in chapters option: %s", value)
+			}
+			depth = d
+			selection = parts[1]
+		}
+
+		if _, err := book.ParseChapters(selection); err != nil {
+			return all, byDepth, err
+		}
+
+		if depth == -1 {
+			all = selection
+		} else {
+			byDepth[depth] = selection
+		}
+	}
+
+	return all, byDepth, nil
+}
+
+// Configs generates the config of each level, use after fillSelectors
+// with a recipe, only the options set on the command line override the recipe ones
+func (o *ScrapeOptions) Configs(cmd *cobra.Command) []*book.ScrapeConfig {
+	// already validated
+	chapters, chaptersByDepth, _ := o.chaptersByDepth()
+
+	set := func(name string) bool {
+		return o.recipe == nil || cmd.Flags().Changed(name)
+	}
+
+	var recipeConfigs []*book.ScrapeConfig
+	if o.recipe != nil {
+		recipeConfigs = o.recipe.Configs()
+	}
+
+	configs := make([]*book.ScrapeConfig, len(o.Selector))
+	for index, s := range o.Selector {
+		config := book.NewScrapeConfig()
+		if o.recipe != nil {
+			config = recipeConfigs[index]
+		}
+
+		config.Depth = index
+		config.Script = o.script
+		config.Renderer = o.renderer
+		if o.recipe == nil || len(s) > 0 {
+			config.Selector = s
+		}
+		if o.recipe == nil || o.limit.isSet(index) {
+			config.Limit = o.limit.at(index)
+		}
+		if o.recipe == nil || o.offset.isSet(index) {
+			config.Offset = o.offset.at(index)
+		}
+		if o.recipe == nil || o.reverse.isSet(index) {
+			config.Reverse = o.reverse.at(index)
+		}
+		if o.recipe == nil || o.delay.isSet(index) {
+			config.Delay = o.delay.at(index)
+		}
+		if o.recipe == nil || o.threads.isSet(index) {
+			config.Threads = o.threads.at(index)
+		}
+		if o.recipe == nil || o.sort.isSet(index) {
+			config.Sort = o.sort.at(index)
+		}
+		if set("use-link-name") {
+			config.UseLinkName = o.useLinkName
+		}
+		if set("href-attr") {
+			config.HrefAttr = o.hrefAttr
+		}
+		if set("title-attr") {
+			config.TitleAttr = o.titleAttr
+		}
+		if set("title-selector") {
+			config.TitleSelector = o.titleSelector
+		}
+		if selection, exists := chaptersByDepth[index]; exists {
+			config.Chapters = selection
+		} else if o.recipe == nil || len(chapters) > 0 {
+			config.Chapters = chapters
+		}
+
+		if extractor, exists, _ := o.extractorAt(index); exists {
+			config.Extractor = extractor
+		}
+
+		// removal rules add up to the recipe ones
+		config.Remove = append(config.Remove, valuesAt(o.remove, index)...)
+		config.RemoveAfter = append(config.RemoveAfter, valuesAt(o.removeAfter, index)...)
+
+		if index == 0 {
+			// candidates are ranked on the first page only, like list candidates option
+			if set("candidate") {
+				config.Candidate = o.candidate - 1
+			}
+			if set("json-links") {
+				config.JSONLinks = o.jsonLinks
+			}
+			if set("json-titles") {
+				config.JSONTitles = o.jsonTitles
+			}
+			if set("json-dates") {
+				config.JSONDates = o.jsonDates
+			}
+			if set("json-next") {
+				config.JSONNext = o.jsonNext
+			}
+			if set("json-cursor-param") {
+				config.JSONCursorParam = o.jsonCursorParam
+			}
+		}
+
+		if set("include") {
+			config.Include = o.include
+
+			// always include last level by default
+			if index == len(o.Selector)-1 {
+				config.Include = true
+			}
+		}
+
+		// do not use link name for root level as there is not parent link
+		if index == 0 {
+			config.UseLinkName = false
+		}
+
+		configs[index] = config
+	}
+
+	return configs
+}
@@ -4,12 +4,18 @@ go 1.17
 
 require (
 	github.com/JohannesKaufmann/html-to-markdown v1.3.6
+	github.com/PaesslerAG/jsonpath v0.1.1
+	github.com/antchfx/xpath v1.2.4
 	github.com/bmaupin/go-epub v1.0.1
 	github.com/elazarl/goproxy v0.0.0-20221015165544-a0805db90819
 	github.com/go-shiori/go-readability v0.0.0-20220215145315-dd6828d2f09b
 	github.com/jedib0t/go-pretty/v6 v6.4.6
 	github.com/mmcdole/gofeed v1.2.1
 	github.com/spf13/cobra v1.6.1
+	github.com/stretchr/testify v1.8.1
+	go.starlark.net v0.0.0-20230302034142-4b1e35fe2254
+	golang.org/x/net v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -20,10 +26,11 @@ require (
 )
 
 require (
+	github.com/PaesslerAG/gval v1.0.0 // indirect
 	github.com/andybalholm/cascadia v1.3.1 // indirect
 	github.com/antchfx/htmlquery v1.3.0 // indirect
 	github.com/antchfx/xmlquery v1.3.15 // indirect
-	github.com/antchfx/xpath v1.2.4 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.1 // indirect
 	github.com/go-shiori/dom v0.0.0-20210627111528-4e4722cd0d65 // indirect
 	github.com/gobwas/glob v0.2.3 // indirect
@@ -39,13 +46,13 @@ require (
 	github.com/mmcdole/goxpp v1.1.0 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/saintfish/chardet v0.0.0-20230101081208-5e3ef4b5456d // indirect
 	github.com/sirupsen/logrus v1.9.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/temoto/robotstxt v1.1.2 // indirect
 	github.com/vincent-petithory/dataurl v1.0.0 // indirect
-	golang.org/x/net v0.8.0 // indirect
 	golang.org/x/sys v0.6.0 // indirect
 	golang.org/x/text v0.8.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect